@@ -0,0 +1,128 @@
+package authz
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/mpapenbr/otlpdemo/log"
+)
+
+// HTTPMiddleware denies requests not allowed by p with 403.
+// The client certificates have to be verified by the TLS config, so the
+// decision is only as good as --tls-client-auth.
+// Note: the decision is added to the span only if the middleware is called
+// after the otelhttp handler
+func HTTPMiddleware(p *Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var certs []*x509.Certificate
+			if r.TLS != nil {
+				certs = r.TLS.PeerCertificates
+			}
+			d := p.AuthorizeHTTP(r.URL.Path, certs)
+			record(r.Context(), r.URL.Path, d, certs)
+			if !d.Allowed {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// UnaryServerInterceptor denies calls not allowed by p with PermissionDenied
+//
+//nolint:whitespace // editor/linter issue
+func UnaryServerInterceptor(p *Policy) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if err := authorizeGRPC(ctx, p, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the streaming variant of UnaryServerInterceptor
+//
+//nolint:whitespace // editor/linter issue
+func StreamServerInterceptor(p *Policy) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if err := authorizeGRPC(ss.Context(), p, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func authorizeGRPC(ctx context.Context, p *Policy, fullMethod string) error {
+	var certs []*x509.Certificate
+	if pr, ok := peer.FromContext(ctx); ok {
+		if info, ok := pr.AuthInfo.(credentials.TLSInfo); ok {
+			certs = info.State.PeerCertificates
+		}
+	}
+	d := p.AuthorizeGRPC(fullMethod, certs)
+	record(ctx, fullMethod, d, certs)
+	if !d.Allowed {
+		return status.Errorf(codes.PermissionDenied, "%s not allowed: %s",
+			fullMethod, d.Reason)
+	}
+	return nil
+}
+
+// record adds the decision to the current span and logs denied requests
+//
+//nolint:whitespace // editor/linter issue
+func record(
+	ctx context.Context,
+	target string,
+	d Decision,
+	certs []*x509.Certificate,
+) {
+	decision := "allow"
+	if !d.Allowed {
+		decision = "deny"
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("authz.decision", decision),
+		attribute.String("authz.reason", d.Reason),
+	}
+	if d.Rule != "" {
+		attrs = append(attrs, attribute.String("authz.rule", d.Rule))
+	}
+	if d.Identity != "" {
+		attrs = append(attrs, attribute.String("authz.identity", d.Identity))
+	}
+	subject := ""
+	if len(certs) > 0 {
+		subject = certs[0].Subject.String()
+		attrs = append(attrs, attribute.String("authz.peer.subject", subject))
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attrs...)
+	if !d.Allowed {
+		log.Info("Request denied by authz policy",
+			log.String("target", target),
+			log.String("rule", d.Rule),
+			log.String("reason", d.Reason),
+			log.String("peerSubject", subject))
+	}
+}
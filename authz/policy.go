@@ -0,0 +1,248 @@
+// Package authz authorizes requests by the client certificate.
+// A policy maps HTTP path prefixes and gRPC full method names to the allowed
+// client identities.
+package authz
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// actions for requests without matching rule
+const (
+	DefaultAllow = "allow"
+	DefaultDeny  = "deny"
+)
+
+// reasons of a decision
+const (
+	ReasonIdentityMatched = "identity matched"
+	ReasonNoIdentity      = "no matching identity"
+	ReasonNoCert          = "no client certificate"
+	ReasonNoRule          = "no matching rule"
+)
+
+type (
+	// Policy is read from a YAML file, e.g.
+	//
+	//	default: deny
+	//	rules:
+	//	  - http: /relay/
+	//	    allow:
+	//	      - cn: democlient
+	//	      - uri: spiffe://otlpdemo/client
+	//	  - grpc: /pet.v1.PetStoreService/GetPet
+	//	    allow:
+	//	      - ou: "^pets( .*)?$"
+	Policy struct {
+		// action for requests without matching rule (allow, deny; default: deny)
+		Default string `yaml:"default"`
+		Rules   []Rule `yaml:"rules"`
+	}
+	// Rule allows the identities to call an HTTP path prefix or a gRPC method.
+	// If several HTTP rules match, the one with the longest prefix is used.
+	Rule struct {
+		HTTPPrefix string     `yaml:"http"` // path prefix
+		GRPCMethod string     `yaml:"grpc"` // full method name
+		Allow      []Identity `yaml:"allow"`
+	}
+	// Identity matches the leaf certificate of the client.
+	// Exactly one of the fields has to be set.
+	Identity struct {
+		CN  string `yaml:"cn"`  // subject common name
+		DNS string `yaml:"dns"` // DNS SAN
+		URI string `yaml:"uri"` // URI SAN, e.g. a SPIFFE ID
+		OU  string `yaml:"ou"`  // regex for the subject organizational units
+		ou  *regexp.Regexp
+	}
+	// Decision is the result of an authorization
+	Decision struct {
+		Allowed  bool
+		Rule     string // HTTP prefix or gRPC method of the matching rule
+		Identity string // the matching identity (e.g. cn=democlient)
+		Reason   string
+	}
+)
+
+// LoadPolicy reads and validates the policy file
+func LoadPolicy(name string) (*Policy, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	p := &Policy{}
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(p); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if err := p.init(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return p, nil
+}
+
+func (p *Policy) init() error {
+	var errs []error
+	switch p.Default {
+	case "":
+		p.Default = DefaultDeny
+	case DefaultAllow, DefaultDeny:
+	default:
+		errs = append(errs, fmt.Errorf("invalid default %q (allow, deny)", p.Default))
+	}
+	for i := range p.Rules {
+		for _, err := range p.Rules[i].init() {
+			errs = append(errs, fmt.Errorf("rule %d: %w", i+1, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Rule) init() []error {
+	var errs []error
+	switch {
+	case (r.HTTPPrefix == "") == (r.GRPCMethod == ""):
+		errs = append(errs, errors.New("exactly one of http and grpc is required"))
+	case r.HTTPPrefix != "" && !strings.HasPrefix(r.HTTPPrefix, "/"):
+		errs = append(errs, fmt.Errorf("http prefix %q must start with /", r.HTTPPrefix))
+	case r.GRPCMethod != "" && strings.Count(r.GRPCMethod, "/") != 2:
+		errs = append(errs, fmt.Errorf(
+			"grpc method %q must be a full method name (/package.Service/Method)",
+			r.GRPCMethod))
+	}
+	if len(r.Allow) == 0 {
+		errs = append(errs, errors.New("no identities allowed"))
+	}
+	for i := range r.Allow {
+		if err := r.Allow[i].init(); err != nil {
+			errs = append(errs, fmt.Errorf("identity %d: %w", i+1, err))
+		}
+	}
+	return errs
+}
+
+func (id *Identity) init() error {
+	set := 0
+	for _, v := range []string{id.CN, id.DNS, id.URI, id.OU} {
+		if v != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return errors.New("exactly one of cn, dns, uri and ou is required")
+	}
+	if id.OU != "" {
+		re, err := regexp.Compile(id.OU)
+		if err != nil {
+			return fmt.Errorf("invalid ou regex: %w", err)
+		}
+		id.ou = re
+	}
+	return nil
+}
+
+// AuthorizeHTTP decides if the client may request reqPath.
+// The cleaned path has to match the prefix at a segment boundary, so /relay/
+// matches /relay and /relay/x but neither /relayx nor /relay/../admin.
+//
+//nolint:whitespace // editor/linter issue
+func (p *Policy) AuthorizeHTTP(
+	reqPath string,
+	certs []*x509.Certificate,
+) Decision {
+	cleaned := path.Clean(reqPath)
+	var rule *Rule
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if r.HTTPPrefix != "" && matchesPrefix(cleaned, r.HTTPPrefix) &&
+			(rule == nil || len(r.HTTPPrefix) > len(rule.HTTPPrefix)) {
+
+			rule = r
+		}
+	}
+	if rule == nil {
+		return p.defaultDecision()
+	}
+	return rule.authorize(rule.HTTPPrefix, certs)
+}
+
+// matchesPrefix reports if the cleaned path p is prefix or below it
+func matchesPrefix(p, prefix string) bool {
+	dir := strings.TrimSuffix(prefix, "/")
+	return p == prefix || p == dir || strings.HasPrefix(p, dir+"/")
+}
+
+// AuthorizeGRPC decides if the client may call the method
+// (e.g. /pet.v1.PetStoreService/GetPet)
+func (p *Policy) AuthorizeGRPC(fullMethod string, certs []*x509.Certificate) Decision {
+	idx := slices.IndexFunc(p.Rules, func(r Rule) bool {
+		return r.GRPCMethod == fullMethod
+	})
+	if idx < 0 {
+		return p.defaultDecision()
+	}
+	return p.Rules[idx].authorize(fullMethod, certs)
+}
+
+func (p *Policy) defaultDecision() Decision {
+	return Decision{Allowed: p.Default == DefaultAllow, Reason: ReasonNoRule}
+}
+
+// authorize checks the leaf of certs against the allowed identities
+func (r *Rule) authorize(name string, certs []*x509.Certificate) Decision {
+	ret := Decision{Rule: name, Reason: ReasonNoCert}
+	if len(certs) == 0 {
+		return ret
+	}
+	ret.Reason = ReasonNoIdentity
+	for _, id := range r.Allow {
+		if id.matches(certs[0]) {
+			ret.Allowed = true
+			ret.Identity = id.String()
+			ret.Reason = ReasonIdentityMatched
+			break
+		}
+	}
+	return ret
+}
+
+func (id *Identity) matches(c *x509.Certificate) bool {
+	switch {
+	case id.CN != "":
+		return c.Subject.CommonName == id.CN
+	case id.DNS != "":
+		return slices.Contains(c.DNSNames, id.DNS)
+	case id.URI != "":
+		return slices.ContainsFunc(c.URIs, func(u *url.URL) bool {
+			return u.String() == id.URI
+		})
+	case id.ou != nil:
+		return slices.ContainsFunc(c.Subject.OrganizationalUnit, id.ou.MatchString)
+	default:
+		return false
+	}
+}
+
+func (id *Identity) String() string {
+	switch {
+	case id.CN != "":
+		return "cn=" + id.CN
+	case id.DNS != "":
+		return "dns=" + id.DNS
+	case id.URI != "":
+		return "uri=" + id.URI
+	default:
+		return "ou=~" + id.OU
+	}
+}
@@ -0,0 +1,232 @@
+package authz
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPolicy = `
+default: deny
+rules:
+  - http: /relay/
+    allow:
+      - cn: democlient
+      - uri: spiffe://otlpdemo/client
+  - http: /relay/admin/
+    allow:
+      - dns: admin.otlpdemo
+  - http: /
+    allow:
+      - ou: "^pets( .*)?$"
+  - grpc: /pet.v1.PetStoreService/GetPet
+    allow:
+      - ou: "^pets( .*)?$"
+`
+
+func writePolicy(t *testing.T, data string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "policy.yml")
+	if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func loadTestPolicy(t *testing.T, data string) *Policy {
+	t.Helper()
+	p, err := LoadPolicy(writePolicy(t, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLoadPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string // substring of the error, empty: no error
+	}{
+		{"valid", testPolicy, ""},
+		{"default deny", "rules: []\n", ""},
+		{"unknown field", "rules: []\nfoo: bar\n", "field foo not found"},
+		{"invalid yaml", "rules: [\n", "policy.yml"},
+		{"invalid default", "default: maybe\n", `invalid default "maybe"`},
+		{
+			"http and grpc",
+			"rules:\n  - http: /a\n    grpc: /a.B/C\n    allow: [{cn: x}]\n",
+			"rule 1: exactly one of http and grpc is required",
+		},
+		{
+			"neither http nor grpc",
+			"rules:\n  - allow: [{cn: x}]\n",
+			"rule 1: exactly one of http and grpc is required",
+		},
+		{
+			"relative http prefix",
+			"rules:\n  - http: relay/\n    allow: [{cn: x}]\n",
+			"must start with /",
+		},
+		{
+			"short grpc method",
+			"rules:\n  - grpc: GetPet\n    allow: [{cn: x}]\n",
+			"must be a full method name",
+		},
+		{
+			"no identities",
+			"rules:\n  - http: /a\n",
+			"rule 1: no identities allowed",
+		},
+		{
+			"two identity fields",
+			"rules:\n  - http: /a\n    allow: [{cn: x, dns: y}]\n",
+			"rule 1: identity 1: exactly one of cn, dns, uri and ou is required",
+		},
+		{
+			"invalid ou regex",
+			"rules:\n  - http: /a\n    allow: [{ou: \"(\"}]\n",
+			"invalid ou regex",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := LoadPolicy(writePolicy(t, tt.data))
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.wantErr != "" && err == nil:
+				t.Fatalf("expected error containing %q", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Fatalf("error %q doesn't contain %q", err, tt.wantErr)
+			case err == nil && p.Default != DefaultDeny:
+				t.Errorf("default = %q, want %q", p.Default, DefaultDeny)
+			}
+		})
+	}
+}
+
+func TestLoadPolicyMissingFile(t *testing.T) {
+	if _, err := LoadPolicy(filepath.Join(t.TempDir(), "missing.yml")); err == nil {
+		t.Error("expected error for a missing file")
+	}
+}
+
+func testCerts() map[string][]*x509.Certificate {
+	spiffe, _ := url.Parse("spiffe://otlpdemo/client")
+	return map[string][]*x509.Certificate{
+		"none":       nil,
+		"democlient": {{Subject: pkix.Name{CommonName: "democlient"}}},
+		"spiffe":     {{Subject: pkix.Name{CommonName: "x"}, URIs: []*url.URL{spiffe}}},
+		"admin": {{Subject: pkix.Name{CommonName: "x"}, DNSNames: []string{
+			"admin.otlpdemo",
+		}}},
+		"pets": {{Subject: pkix.Name{
+			CommonName:         "x",
+			OrganizationalUnit: []string{"dev", "pets shop"},
+		}}},
+		"other": {{Subject: pkix.Name{CommonName: "other"}}},
+	}
+}
+
+func TestAuthorizeHTTP(t *testing.T) {
+	p := loadTestPolicy(t, testPolicy)
+	certs := testCerts()
+	tests := []struct {
+		name     string
+		path     string
+		cert     string
+		want     bool
+		wantRule string
+		reason   string
+	}{
+		{"cn", "/relay/x", "democlient", true, "/relay/", ReasonIdentityMatched},
+		{"uri", "/relay/x", "spiffe", true, "/relay/", ReasonIdentityMatched},
+		{"no identity", "/relay/x", "other", false, "/relay/", ReasonNoIdentity},
+		{"no cert", "/relay/x", "none", false, "/relay/", ReasonNoCert},
+		{"prefix itself", "/relay/", "democlient", true, "/relay/", ReasonIdentityMatched},
+		{"without slash", "/relay", "democlient", true, "/relay/", ReasonIdentityMatched},
+		{"longest prefix", "/relay/admin/x", "admin", true, "/relay/admin/",
+			ReasonIdentityMatched},
+		{"longest prefix only", "/relay/admin/x", "democlient", false,
+			"/relay/admin/", ReasonNoIdentity},
+		// segment boundaries
+		{"no partial segment", "/relayx", "democlient", false, "/", ReasonNoIdentity},
+		{"no partial segment below", "/relay/adminx", "democlient", true, "/relay/",
+			ReasonIdentityMatched},
+		{"dot dot", "/relay/../admin", "democlient", false, "/", ReasonNoIdentity},
+		{"dot dot to admin", "/relay/x/../admin/y", "democlient", false,
+			"/relay/admin/", ReasonNoIdentity},
+		{"double slash", "//relay//x", "democlient", true, "/relay/",
+			ReasonIdentityMatched},
+		{"root", "/", "pets", true, "/", ReasonIdentityMatched},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := p.AuthorizeHTTP(tt.path, certs[tt.cert])
+			if d.Allowed != tt.want || d.Rule != tt.wantRule || d.Reason != tt.reason {
+				t.Errorf("got %+v, want allowed=%t rule=%s reason=%s",
+					d, tt.want, tt.wantRule, tt.reason)
+			}
+		})
+	}
+}
+
+func TestAuthorizeGRPC(t *testing.T) {
+	p := loadTestPolicy(t, testPolicy)
+	certs := testCerts()
+	const getPet = "/pet.v1.PetStoreService/GetPet"
+	tests := []struct {
+		name   string
+		method string
+		cert   string
+		want   bool
+		reason string
+	}{
+		{"ou", getPet, "pets", true, ReasonIdentityMatched},
+		{"no identity", getPet, "democlient", false, ReasonNoIdentity},
+		{"no cert", getPet, "none", false, ReasonNoCert},
+		{"exact match only", getPet + "s", "pets", false, ReasonNoRule},
+		{"no rule", "/pet.v1.PetStoreService/AddPet", "pets", false, ReasonNoRule},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := p.AuthorizeGRPC(tt.method, certs[tt.cert])
+			if d.Allowed != tt.want || d.Reason != tt.reason {
+				t.Errorf("got %+v, want allowed=%t reason=%s", d, tt.want, tt.reason)
+			}
+		})
+	}
+}
+
+func TestDefaultDecision(t *testing.T) {
+	certs := testCerts()
+	for _, tt := range []struct {
+		def  string
+		want bool
+	}{
+		{DefaultAllow, true},
+		{DefaultDeny, false},
+	} {
+		p := loadTestPolicy(t, "default: "+tt.def+`
+rules:
+  - http: /relay/
+    allow: [{cn: democlient}]
+`)
+		for _, cert := range []string{"democlient", "none"} {
+			if d := p.AuthorizeHTTP("/relayx", certs[cert]); d.Allowed != tt.want ||
+				d.Reason != ReasonNoRule {
+
+				t.Errorf("%s/%s: got %+v, want allowed=%t", tt.def, cert, d, tt.want)
+			}
+			d := p.AuthorizeGRPC("/pet.v1.PetStoreService/GetPet", certs[cert])
+			if d.Allowed != tt.want || d.Reason != ReasonNoRule {
+				t.Errorf("%s/%s: got %+v, want allowed=%t", tt.def, cert, d, tt.want)
+			}
+		}
+	}
+}
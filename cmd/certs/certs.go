@@ -0,0 +1,390 @@
+package certs
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const day = 24 * time.Hour
+
+var (
+	outDir  string
+	keyType string
+	rsaBits int
+)
+
+type (
+	caOptions struct {
+		name           string
+		cn             string
+		org            string
+		days           int
+		intermediate   bool
+		intermediateCN string
+	}
+	leafOptions struct {
+		name  string
+		cn    string
+		org   string
+		dns   []string
+		ips   []net.IP
+		uris  []string
+		days  int
+		ca    string
+		caKey string
+	}
+)
+
+func NewCertsCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "certs",
+		Short: "create certificates for the TLS demos",
+		Long: `Creates CA, server and client certificates like createCerts.sh.
+The keys are written unencrypted (PKCS #8) with file mode 0600.`,
+	}
+	cmd.PersistentFlags().StringVar(&outDir, "out", "certs", "output directory")
+	cmd.PersistentFlags().StringVar(&keyType, "key-type", KeyTypeRSA,
+		"key type of new keys (rsa, ecdsa, ed25519)")
+	cmd.PersistentFlags().IntVar(&rsaBits, "rsa-bits", 2048, "size of new RSA keys")
+
+	cmd.AddCommand(newCACommand())
+	cmd.AddCommand(newServerCommand())
+	cmd.AddCommand(newClientCommand())
+	cmd.AddCommand(newAllCommand())
+	cmd.AddCommand(newRotateCommand())
+	cmd.AddCommand(newInspectCommand())
+	return &cmd
+}
+
+func newCACommand() *cobra.Command {
+	opts := caOptions{}
+	cmd := cobra.Command{
+		Use:   "ca",
+		Short: "create a root CA and an optional intermediate CA",
+		Long: `Creates <out>/<name>.pem and <out>/<name>.key.
+With --intermediate <out>/intermediateCA.pem and .key are created too.
+Certificates issued by the intermediate CA contain it in their chain.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return createCAs(opts)
+		},
+	}
+	cmd.Flags().StringVar(&opts.name, "name", "rootCA", "base name of the files")
+	cmd.Flags().StringVar(&opts.cn, "cn", "otlpdemo root CA", "common name")
+	cmd.Flags().StringVar(&opts.org, "org", "otlpdemo", "organization")
+	cmd.Flags().IntVar(&opts.days, "days", 3650, "validity in days")
+	cmd.Flags().BoolVar(&opts.intermediate, "intermediate", false,
+		"create an intermediate CA signed by the root CA")
+	cmd.Flags().StringVar(&opts.intermediateCN, "intermediate-cn",
+		"otlpdemo intermediate CA", "common name of the intermediate CA")
+	return &cmd
+}
+
+func newServerCommand() *cobra.Command {
+	opts := leafOptions{}
+	cmd := cobra.Command{
+		Use:          "server",
+		Short:        "create a server certificate (<out>/<name>.crt and .key)",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			iss, err := opts.issuer()
+			if err != nil {
+				return err
+			}
+			req := serverRequest(opts.cn, opts.org, opts.dns, opts.ips)
+			req.Validity = time.Duration(opts.days) * day
+			return createLeaf(filepath.Join(outDir, opts.name), req, iss)
+		},
+	}
+	opts.addFlags(&cmd, "server", "somelocalhost")
+	cmd.Flags().StringSliceVar(&opts.dns, "dns", []string{"localhost"}, "DNS SANs")
+	cmd.Flags().IPSliceVar(&opts.ips, "ip", []net.IP{net.IPv4(127, 0, 0, 1)},
+		"IP SANs")
+	return &cmd
+}
+
+func newClientCommand() *cobra.Command {
+	opts := leafOptions{}
+	cmd := cobra.Command{
+		Use:          "client",
+		Short:        "create a client certificate (<out>/<name>.crt and .key)",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			iss, err := opts.issuer()
+			if err != nil {
+				return err
+			}
+			req := clientRequest(opts.cn, opts.org)
+			req.Validity = time.Duration(opts.days) * day
+			for _, u := range opts.uris {
+				parsed, err := url.Parse(u)
+				if err != nil {
+					return fmt.Errorf("invalid URI SAN %q: %w", u, err)
+				}
+				req.URIs = append(req.URIs, parsed)
+			}
+			return createLeaf(filepath.Join(outDir, opts.name), req, iss)
+		},
+	}
+	opts.addFlags(&cmd, "client", "democlient")
+	cmd.Flags().StringSliceVar(&opts.uris, "uri", []string{},
+		"URI SANs (e.g. spiffe://otlpdemo/client)")
+	return &cmd
+}
+
+func newAllCommand() *cobra.Command {
+	days := 0
+	cmd := cobra.Command{
+		Use:   "all",
+		Short: "create all certificates of the demos (like createCerts.sh)",
+		Long: `Creates the layout used in README-tls.md and the otlp-env-secure*.env files:
+  <out>/rootCA.pem, server.crt/.key, client.crt/.key
+  <out>/special/clientRootCA.pem, client.crt/.key (client cert with own CA)
+  <out>/docker/rootCA.pem, server.crt/.key, client.crt/.key`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return createAll(time.Duration(days) * day)
+		},
+	}
+	cmd.Flags().IntVar(&days, "days", 365, "validity of server and client certs in days")
+	return &cmd
+}
+
+func newRotateCommand() *cobra.Command {
+	opts := leafOptions{}
+	cmd := cobra.Command{
+		Use:   "rotate [cert files...]",
+		Short: "reissue certificates in place",
+		Long: `Reissues the given certificates (default: <out>/server.crt and
+<out>/client.crt) with a new key of the same type. Subject, SANs and key usage
+are kept. The key is expected next to the certificate (<name>.key).
+The files are replaced atomically, so a running TLSReloader picks them up.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				args = []string{
+					filepath.Join(outDir, "server.crt"),
+					filepath.Join(outDir, "client.crt"),
+				}
+			}
+			iss, err := opts.issuer()
+			if err != nil {
+				return err
+			}
+			for _, name := range args {
+				if err := rotate(name, iss, time.Duration(opts.days)*day); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&opts.days, "days", 0,
+		"validity in days (default: validity of the current cert)")
+	opts.addCAFlags(&cmd)
+	return &cmd
+}
+
+func (o *leafOptions) addFlags(cmd *cobra.Command, name, cn string) {
+	cmd.Flags().StringVar(&o.name, "name", name, "base name of the files")
+	cmd.Flags().StringVar(&o.cn, "cn", cn, "common name")
+	cmd.Flags().StringVar(&o.org, "org", "otlpdemo", "organization")
+	cmd.Flags().IntVar(&o.days, "days", 365, "validity in days")
+	o.addCAFlags(cmd)
+}
+
+func (o *leafOptions) addCAFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.ca, "ca", "",
+		"CA certificate to sign with (default: <out>/rootCA.pem)")
+	cmd.Flags().StringVar(&o.caKey, "ca-key", "",
+		"key of the CA (default: the CA file with extension .key)")
+}
+
+func (o *leafOptions) issuer() (*issuer, error) {
+	ca := o.ca
+	if ca == "" {
+		ca = filepath.Join(outDir, "rootCA.pem")
+	}
+	caKey := o.caKey
+	if caKey == "" {
+		caKey = strings.TrimSuffix(ca, filepath.Ext(ca)) + ".key"
+	}
+	return loadIssuer(ca, caKey)
+}
+
+func serverRequest(cn, org string, dns []string, ips []net.IP) certRequest {
+	return certRequest{
+		CommonName:   cn,
+		Organization: org,
+		DNSNames:     dns,
+		IPs:          ips,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+}
+
+func clientRequest(cn, org string) certRequest {
+	return certRequest{
+		CommonName:   cn,
+		Organization: org,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+}
+
+func createCAs(opts caOptions) error {
+	root, err := createCA(filepath.Join(outDir, opts.name), certRequest{
+		CommonName:   opts.cn,
+		Organization: opts.org,
+		IsCA:         true,
+		Validity:     time.Duration(opts.days) * day,
+	}, nil)
+	if err != nil || !opts.intermediate {
+		return err
+	}
+	_, err = createCA(filepath.Join(outDir, "intermediateCA"), certRequest{
+		CommonName:   opts.intermediateCN,
+		Organization: opts.org,
+		IsCA:         true,
+		Validity:     time.Duration(opts.days) * day,
+	}, root)
+	return err
+}
+
+//nolint:funlen // the layout of createCerts.sh
+func createAll(validity time.Duration) error {
+	caValidity := 3650 * day
+	leaf := func(req certRequest) certRequest {
+		req.Validity = validity
+		return req
+	}
+	root, err := createCA(filepath.Join(outDir, "rootCA"), certRequest{
+		CommonName: "otlpdemo root CA", Organization: "otlpdemo",
+		IsCA: true, Validity: caValidity,
+	}, nil)
+	if err != nil {
+		return err
+	}
+	if err := createLeaf(filepath.Join(outDir, "server"),
+		leaf(serverRequest("somelocalhost", "otlpdemo",
+			[]string{"localhost"}, []net.IP{net.IPv4(127, 0, 0, 1)})),
+		root); err != nil {
+		return err
+	}
+	if err := createLeaf(filepath.Join(outDir, "client"),
+		leaf(clientRequest("democlient", "otlpdemo")), root); err != nil {
+		return err
+	}
+
+	// special: client cert with own CA
+	special, err := createCA(filepath.Join(outDir, "special", "clientRootCA"),
+		certRequest{
+			CommonName: "otlpdemo client root CA", Organization: "otlpdemo clients",
+			IsCA: true, Validity: caValidity,
+		}, nil)
+	if err != nil {
+		return err
+	}
+	if err := createLeaf(filepath.Join(outDir, "special", "client"),
+		leaf(clientRequest("special democlient", "otlpdemo clients")),
+		special); err != nil {
+		return err
+	}
+
+	// for mtls within docker compose setup we use dedicated certs
+	docker, err := createCA(filepath.Join(outDir, "docker", "rootCA"),
+		certRequest{
+			CommonName: "otlpdemo docker root CA", Organization: "otlpdemo docker",
+			IsCA: true, Validity: caValidity,
+		}, nil)
+	if err != nil {
+		return err
+	}
+	if err := createLeaf(filepath.Join(outDir, "docker", "server"),
+		leaf(serverRequest("common docker cert", "otlpdemo",
+			[]string{"prometheus", "tempo", "loki"}, nil)),
+		docker); err != nil {
+		return err
+	}
+	return createLeaf(filepath.Join(outDir, "docker", "client"),
+		leaf(clientRequest("otlpclient", "otlpdemo")), docker)
+}
+
+// createCA writes <base>.pem and <base>.key and returns the new CA
+func createCA(base string, req certRequest, iss *issuer) (*issuer, error) {
+	key, err := generateKey(keySpec{Type: keyType, RSABits: rsaBits})
+	if err != nil {
+		return nil, err
+	}
+	c, err := issue(req, key, iss)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeCertAndKey(base, "pem", []*x509.Certificate{c}, key); err != nil {
+		return nil, err
+	}
+	report(base+".pem", c)
+	return &issuer{cert: c, key: key}, nil
+}
+
+// createLeaf writes <base>.crt (including intermediates) and <base>.key
+func createLeaf(base string, req certRequest, iss *issuer) error {
+	key, err := generateKey(keySpec{Type: keyType, RSABits: rsaBits})
+	if err != nil {
+		return err
+	}
+	c, err := issue(req, key, iss)
+	if err != nil {
+		return err
+	}
+	if err := writeCertAndKey(base, "crt", iss.chain(c), key); err != nil {
+		return err
+	}
+	report(base+".crt", c)
+	return nil
+}
+
+// rotate reissues the certificate in file name
+func rotate(name string, iss *issuer, validity time.Duration) error {
+	old, err := readCertificate(name)
+	if err != nil {
+		return err
+	}
+	if old.IsCA {
+		return fmt.Errorf("%s: CA certificates are not rotated, use 'certs ca'", name)
+	}
+	spec, err := keySpecOf(old.PublicKey)
+	if err != nil {
+		return err
+	}
+	key, err := generateKey(spec)
+	if err != nil {
+		return err
+	}
+	req := requestOf(old)
+	if validity > 0 {
+		req.Validity = validity
+	}
+	c, err := issue(req, key, iss)
+	if err != nil {
+		return err
+	}
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	if err := writeCertAndKey(base, strings.TrimPrefix(filepath.Ext(name), "."),
+		iss.chain(c), key); err != nil {
+
+		return err
+	}
+	report(name, c)
+	return nil
+}
+
+func report(name string, c *x509.Certificate) {
+	fmt.Printf("%s: %s (serial %s, valid until %s)\n",
+		name, c.Subject, c.SerialNumber.Text(16), c.NotAfter.Format(time.RFC3339))
+}
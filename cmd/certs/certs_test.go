@@ -0,0 +1,186 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mpapenbr/otlpdemo/cmd/config"
+)
+
+// useOutDir sets the output directory and the key type of the commands
+func useOutDir(t *testing.T) string {
+	t.Helper()
+	oldDir, oldType := outDir, keyType
+	t.Cleanup(func() { outDir, keyType = oldDir, oldType })
+	outDir, keyType = t.TempDir(), KeyTypeECDSA
+	return outDir
+}
+
+func loadPair(t *testing.T, certFile string) tls.Certificate {
+	t.Helper()
+	keyFile := certFile[:len(certFile)-len(filepath.Ext(certFile))] + ".key"
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pair
+}
+
+func verify(t *testing.T, pair tls.Certificate, caFile string, usage x509.ExtKeyUsage) {
+	t.Helper()
+	roots, err := readCertificates(caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := x509.VerifyOptions{
+		Roots:         x509.NewCertPool(),
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	}
+	opts.Roots.AddCert(roots[0])
+	for _, der := range pair.Certificate[1:] {
+		c, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts.Intermediates.AddCert(c)
+	}
+	if _, err := pair.Leaf.Verify(opts); err != nil {
+		t.Errorf("%s: %v", pair.Leaf.Subject, err)
+	}
+}
+
+func TestCreateAllLayout(t *testing.T) {
+	dir := useOutDir(t)
+	if err := createAll(day); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		cert, ca string
+		usage    x509.ExtKeyUsage
+	}{
+		{"server.crt", "rootCA.pem", x509.ExtKeyUsageServerAuth},
+		{"client.crt", "rootCA.pem", x509.ExtKeyUsageClientAuth},
+		{"special/client.crt", "special/clientRootCA.pem", x509.ExtKeyUsageClientAuth},
+		{"docker/server.crt", "docker/rootCA.pem", x509.ExtKeyUsageServerAuth},
+		{"docker/client.crt", "docker/rootCA.pem", x509.ExtKeyUsageClientAuth},
+	}
+	for _, tt := range tests {
+		pair := loadPair(t, filepath.Join(dir, tt.cert))
+		verify(t, pair, filepath.Join(dir, tt.ca), tt.usage)
+	}
+	for _, key := range []string{"rootCA.key", "server.key", "docker/client.key"} {
+		info, err := os.Stat(filepath.Join(dir, key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0o600 {
+			t.Errorf("%s: mode %v, want 0600", key, info.Mode().Perm())
+		}
+	}
+}
+
+// CA -> intermediate -> server/client -> rotate
+func TestRotateRoundTrip(t *testing.T) {
+	dir := useOutDir(t)
+	if err := createCAs(caOptions{
+		name: "rootCA", cn: "root", org: "test", days: 10,
+		intermediate: true, intermediateCN: "intermediate",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	iss, err := loadIssuer(filepath.Join(dir, "intermediateCA.pem"),
+		filepath.Join(dir, "intermediateCA.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverReq := serverRequest("server", "test", []string{"localhost"}, nil)
+	serverReq.Validity = day
+	clientReq := clientRequest("client", "test")
+	clientReq.Validity = day
+	serverFile := filepath.Join(dir, "server.crt")
+	clientFile := filepath.Join(dir, "client.crt")
+	if err := createLeaf(filepath.Join(dir, "server"), serverReq, iss); err != nil {
+		t.Fatal(err)
+	}
+	if err := createLeaf(filepath.Join(dir, "client"), clientReq, iss); err != nil {
+		t.Fatal(err)
+	}
+	before := map[string]tls.Certificate{
+		serverFile: loadPair(t, serverFile),
+		clientFile: loadPair(t, clientFile),
+	}
+
+	for _, name := range []string{serverFile, clientFile} {
+		if err := rotate(name, iss, 0); err != nil {
+			t.Fatal(err)
+		}
+		old, pair := before[name], loadPair(t, name)
+		if len(pair.Certificate) != 2 {
+			t.Errorf("%s: chain of %d certs, want leaf and intermediate", name,
+				len(pair.Certificate))
+		}
+		if pair.Leaf.SerialNumber.Cmp(old.Leaf.SerialNumber) == 0 {
+			t.Errorf("%s: serial not changed", name)
+		}
+		if pair.Leaf.Subject.String() != old.Leaf.Subject.String() ||
+			!slices.Equal(pair.Leaf.DNSNames, old.Leaf.DNSNames) ||
+			!slices.Equal(pair.Leaf.ExtKeyUsage, old.Leaf.ExtKeyUsage) {
+
+			t.Errorf("%s: subject, SANs or usage changed", name)
+		}
+		verify(t, pair, filepath.Join(dir, "rootCA.pem"), old.Leaf.ExtKeyUsage[0])
+	}
+}
+
+// a running reloader picks up the rotated cert and key without a failure
+func TestRotateWithReloader(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the reload delay")
+	}
+	dir := useOutDir(t)
+	if err := createAll(day); err != nil {
+		t.Fatal(err)
+	}
+	serverFile := filepath.Join(dir, "server.crt")
+	r, err := config.NewTLSReloader(config.TLSConfig{
+		MinVersion: "TLS13",
+		Cert:       serverFile,
+		Key:        filepath.Join(dir, "server.key"),
+		CAs:        []string{filepath.Join(dir, "rootCA.pem")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.Start(ctx)
+	time.Sleep(100 * time.Millisecond) // the watcher is set up in the background
+
+	iss, err := loadIssuer(filepath.Join(dir, "rootCA.pem"),
+		filepath.Join(dir, "rootCA.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rotate(serverFile, iss, 0); err != nil {
+		t.Fatal(err)
+	}
+	want := config.Fingerprint(loadPair(t, serverFile).Leaf)
+	deadline := time.Now().Add(5 * time.Second)
+	for r.Status().Fingerprint != want && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	status := r.Status()
+	if status.Fingerprint != want {
+		t.Errorf("rotated cert not reloaded: %+v", status)
+	}
+	if status.Failures != 0 {
+		t.Errorf("failed reloads: %+v", status)
+	}
+}
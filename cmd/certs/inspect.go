@@ -0,0 +1,382 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mpapenbr/otlpdemo/cmd/config"
+)
+
+// usage labels of certificates from --tls-cert
+const (
+	usageLeaf         = "leaf"
+	usageIntermediate = "intermediate"
+)
+
+type (
+	inspectOptions struct {
+		cert      string
+		key       string
+		cas       []string
+		clientCAs []string
+		usages    []string
+		output    string
+	}
+	certInfo struct {
+		File        string    `json:"file"`
+		Usage       string    `json:"usage"`
+		Subject     string    `json:"subject"`
+		Issuer      string    `json:"issuer"`
+		Serial      string    `json:"serial"`
+		DNSNames    []string  `json:"dnsNames,omitempty"`
+		IPs         []string  `json:"ips,omitempty"`
+		URIs        []string  `json:"uris,omitempty"`
+		Emails      []string  `json:"emails,omitempty"`
+		NotBefore   time.Time `json:"notBefore"`
+		NotAfter    time.Time `json:"notAfter"`
+		Status      string    `json:"status"` // valid, expired, not yet valid
+		IsCA        bool      `json:"isCA"`
+		PublicKey   string    `json:"publicKey"`
+		KeyUsage    []string  `json:"keyUsage,omitempty"`
+		ExtKeyUsage []string  `json:"extKeyUsage,omitempty"`
+		Fingerprint string    `json:"sha256"`
+	}
+	checkResult struct {
+		Name  string `json:"name"`
+		OK    bool   `json:"ok"`
+		Error string `json:"error,omitempty"`
+	}
+	inspectReport struct {
+		Certificates []certInfo    `json:"certificates"`
+		Checks       []checkResult `json:"checks"`
+	}
+)
+
+func newInspectCommand() *cobra.Command {
+	opts := inspectOptions{}
+	cmd := cobra.Command{
+		Use:   "inspect",
+		Short: "show certificates and check if they fit together",
+		Long: `Loads the given files like the TLS servers and clients do and prints
+subject, SANs, issuer, validity, key usage and SHA-256 fingerprint of each
+certificate. With --tls-key the key is checked against the certificate.
+The chain of --tls-cert is verified for each --usage: server-auth against
+--tls-ca, client-auth against --tls-client-ca (default: --tls-ca). Without CAs
+the system roots are used. The command fails if a check fails.`,
+		// a cert without key is fine here, so the root validation is skipped
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+		SilenceUsage:     true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return inspect(os.Stdout, opts)
+		},
+	}
+	cmd.Flags().StringVar(&opts.cert, "tls-cert", "",
+		"certificate file (may contain intermediates)")
+	cmd.Flags().StringVar(&opts.key, "tls-key", "", "key of the certificate")
+	cmd.Flags().StringSliceVar(&opts.cas, "tls-ca", []string{},
+		"CA files to verify server certificates")
+	cmd.Flags().StringSliceVar(&opts.clientCAs, "tls-client-ca", []string{},
+		"CA files to verify client certificates")
+	cmd.Flags().StringSliceVar(&opts.usages, "usage", []string{},
+		"usages to verify (server, client; default: by ext key usage of the cert)")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "text",
+		"output format (text, json)")
+	return &cmd
+}
+
+//nolint:funlen // by design
+func inspect(w io.Writer, opts inspectOptions) error {
+	if opts.key != "" && opts.cert == "" {
+		return errors.New("--tls-key requires --tls-cert")
+	}
+	if opts.cert == "" && len(opts.cas) == 0 && len(opts.clientCAs) == 0 {
+		return errors.New("nothing to inspect, use --tls-cert, --tls-ca or --tls-client-ca")
+	}
+	format := strings.ToLower(opts.output)
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unknown output format %q", opts.output)
+	}
+	caCfg, loaded, err := config.LoadTLSConfig(config.TLSConfig{
+		MinVersion: "tls13",
+		CAs:        opts.cas,
+		ClientCAs:  opts.clientCAs,
+	}, usageLeaf)
+	if err != nil {
+		return err
+	}
+
+	rep := inspectReport{Certificates: []certInfo{}, Checks: []checkResult{}}
+	now := time.Now()
+	if opts.cert != "" {
+		chain, err := readCertificates(opts.cert)
+		if err != nil {
+			return err
+		}
+		for i, c := range chain {
+			usage := usageLeaf
+			if i > 0 {
+				usage = usageIntermediate
+			}
+			rep.Certificates = append(rep.Certificates, newCertInfo(opts.cert, usage, c, now))
+		}
+		if opts.key != "" {
+			// the key pair is loaded like the TLS builders do it
+			_, _, err := config.LoadTLSConfig(config.TLSConfig{
+				MinVersion: "tls13",
+				Cert:       opts.cert,
+				Key:        opts.key,
+			}, usageLeaf)
+			rep.Checks = append(rep.Checks, newCheckResult("key matches certificate", err))
+		}
+		usages, err := verifyUsages(opts.usages, chain[0])
+		if err != nil {
+			return err
+		}
+		for _, usage := range usages {
+			rep.Checks = append(rep.Checks, verifyChain(chain, usage, opts, caCfg.RootCAs,
+				caCfg.ClientCAs, now))
+		}
+	}
+	for _, l := range loaded {
+		for _, c := range l.Certs {
+			rep.Certificates = append(rep.Certificates, newCertInfo(l.Source, l.Usage, c, now))
+		}
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(rep)
+	} else {
+		err = writeText(w, rep)
+	}
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, c := range rep.Checks {
+		if !c.OK {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(rep.Checks))
+	}
+	return nil
+}
+
+// verifyUsages returns the usages to verify the leaf for
+func verifyUsages(usages []string, leaf *x509.Certificate) ([]string, error) {
+	for _, u := range usages {
+		if u != config.CertUsageServer && u != config.CertUsageClient {
+			return nil, fmt.Errorf("unsupported usage %q (server, client)", u)
+		}
+	}
+	if len(usages) > 0 {
+		return usages, nil
+	}
+	var ret []string
+	if slices.Contains(leaf.ExtKeyUsage, x509.ExtKeyUsageServerAuth) {
+		ret = append(ret, config.CertUsageServer)
+	}
+	if slices.Contains(leaf.ExtKeyUsage, x509.ExtKeyUsageClientAuth) {
+		ret = append(ret, config.CertUsageClient)
+	}
+	if len(ret) == 0 {
+		// no or unrestricted ext key usage
+		ret = []string{config.CertUsageServer, config.CertUsageClient}
+	}
+	return ret, nil
+}
+
+// verifyChain verifies chain for usage against the roots of the usage
+//
+//nolint:whitespace // editor/linter issue
+func verifyChain(
+	chain []*x509.Certificate,
+	usage string,
+	opts inspectOptions,
+	rootCAs, clientCAs *x509.CertPool,
+	now time.Time,
+) checkResult {
+	vo := x509.VerifyOptions{
+		Intermediates: x509.NewCertPool(),
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		Roots:         rootCAs,
+	}
+	roots := "--tls-ca"
+	if usage == config.CertUsageClient {
+		vo.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+		if len(opts.clientCAs) > 0 {
+			vo.Roots, roots = clientCAs, "--tls-client-ca"
+		}
+	}
+	if vo.Roots == nil {
+		roots = "system roots"
+	}
+	for _, c := range chain[1:] {
+		vo.Intermediates.AddCert(c)
+	}
+	_, err := chain[0].Verify(vo)
+	return newCheckResult(fmt.Sprintf("chain verifies for %s-auth (%s)", usage, roots),
+		err)
+}
+
+func newCheckResult(name string, err error) checkResult {
+	if err != nil {
+		return checkResult{Name: name, Error: err.Error()}
+	}
+	return checkResult{Name: name, OK: true}
+}
+
+//nolint:whitespace // editor/linter issue
+func newCertInfo(
+	file, usage string,
+	c *x509.Certificate,
+	now time.Time,
+) certInfo {
+	info := certInfo{
+		File:        file,
+		Usage:       usage,
+		Subject:     c.Subject.String(),
+		Issuer:      c.Issuer.String(),
+		Serial:      c.SerialNumber.Text(16),
+		DNSNames:    c.DNSNames,
+		Emails:      c.EmailAddresses,
+		NotBefore:   c.NotBefore,
+		NotAfter:    c.NotAfter,
+		Status:      "valid",
+		IsCA:        c.IsCA,
+		PublicKey:   publicKeyName(c.PublicKey),
+		KeyUsage:    keyUsageNames(c.KeyUsage),
+		ExtKeyUsage: extKeyUsageNames(c),
+		Fingerprint: config.Fingerprint(c),
+	}
+	for _, ip := range c.IPAddresses {
+		info.IPs = append(info.IPs, ip.String())
+	}
+	for _, u := range c.URIs {
+		info.URIs = append(info.URIs, u.String())
+	}
+	switch {
+	case now.Before(c.NotBefore):
+		info.Status = "not yet valid"
+	case now.After(c.NotAfter):
+		info.Status = "expired"
+	}
+	return info
+}
+
+func publicKeyName(pub any) string {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", k.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA " + k.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return fmt.Sprintf("%T", pub)
+	}
+}
+
+var keyUsages = []struct {
+	usage x509.KeyUsage
+	name  string
+}{
+	{x509.KeyUsageDigitalSignature, "digitalSignature"},
+	{x509.KeyUsageContentCommitment, "contentCommitment"},
+	{x509.KeyUsageKeyEncipherment, "keyEncipherment"},
+	{x509.KeyUsageDataEncipherment, "dataEncipherment"},
+	{x509.KeyUsageKeyAgreement, "keyAgreement"},
+	{x509.KeyUsageCertSign, "certSign"},
+	{x509.KeyUsageCRLSign, "crlSign"},
+	{x509.KeyUsageEncipherOnly, "encipherOnly"},
+	{x509.KeyUsageDecipherOnly, "decipherOnly"},
+}
+
+func keyUsageNames(ku x509.KeyUsage) []string {
+	var ret []string
+	for _, k := range keyUsages {
+		if ku&k.usage != 0 {
+			ret = append(ret, k.name)
+		}
+	}
+	return ret
+}
+
+var extKeyUsages = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:             "any",
+	x509.ExtKeyUsageServerAuth:      "serverAuth",
+	x509.ExtKeyUsageClientAuth:      "clientAuth",
+	x509.ExtKeyUsageCodeSigning:     "codeSigning",
+	x509.ExtKeyUsageEmailProtection: "emailProtection",
+	x509.ExtKeyUsageTimeStamping:    "timeStamping",
+	x509.ExtKeyUsageOCSPSigning:     "OCSPSigning",
+}
+
+func extKeyUsageNames(c *x509.Certificate) []string {
+	var ret []string
+	for _, eku := range c.ExtKeyUsage {
+		if name, ok := extKeyUsages[eku]; ok {
+			ret = append(ret, name)
+		} else {
+			ret = append(ret, fmt.Sprintf("eku(%d)", eku))
+		}
+	}
+	for _, oid := range c.UnknownExtKeyUsage {
+		ret = append(ret, oid.String())
+	}
+	return ret
+}
+
+func writeText(w io.Writer, rep inspectReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	line := func(name string, values ...string) {
+		if len(values) > 0 {
+			fmt.Fprintf(tw, "  %s:\t%s\n", name, strings.Join(values, ", "))
+		}
+	}
+	for _, c := range rep.Certificates {
+		fmt.Fprintf(tw, "%s (%s)\n", c.File, c.Usage)
+		line("subject", c.Subject)
+		line("issuer", c.Issuer)
+		line("serial", c.Serial)
+		line("DNS SANs", c.DNSNames...)
+		line("IP SANs", c.IPs...)
+		line("URI SANs", c.URIs...)
+		line("email SANs", c.Emails...)
+		line("validity", fmt.Sprintf("%s - %s (%s)",
+			c.NotBefore.Format(time.RFC3339), c.NotAfter.Format(time.RFC3339), c.Status))
+		line("CA", fmt.Sprint(c.IsCA))
+		line("public key", c.PublicKey)
+		line("key usage", c.KeyUsage...)
+		line("ext key usage", c.ExtKeyUsage...)
+		line("sha256", c.Fingerprint)
+	}
+	if len(rep.Checks) > 0 {
+		fmt.Fprintln(tw, "checks")
+	}
+	for _, c := range rep.Checks {
+		result := "ok"
+		if !c.OK {
+			result = "FAILED: " + c.Error
+		}
+		fmt.Fprintf(tw, "  %s:\t%s\n", c.Name, result)
+	}
+	return tw.Flush()
+}
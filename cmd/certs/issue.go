@@ -0,0 +1,262 @@
+package certs
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mpapenbr/otlpdemo/cmd/config"
+)
+
+// supported key types
+const (
+	KeyTypeRSA     = "rsa"
+	KeyTypeECDSA   = "ecdsa"
+	KeyTypeED25519 = "ed25519"
+)
+
+type (
+	// certRequest describes the certificate to be issued
+	certRequest struct {
+		CommonName   string
+		Organization string
+		DNSNames     []string
+		IPs          []net.IP
+		URIs         []*url.URL
+		IsCA         bool
+		ExtKeyUsage  []x509.ExtKeyUsage
+		Validity     time.Duration
+	}
+	// issuer signs certificates
+	issuer struct {
+		cert *x509.Certificate
+		key  crypto.Signer
+	}
+	// keySpec defines the key to generate
+	keySpec struct {
+		Type    string
+		RSABits int
+	}
+)
+
+func generateKey(spec keySpec) (crypto.Signer, error) {
+	switch strings.ToLower(spec.Type) {
+	case KeyTypeRSA:
+		return rsa.GenerateKey(rand.Reader, spec.RSABits)
+	case KeyTypeECDSA:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyTypeED25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", spec.Type)
+	}
+}
+
+// keySpecOf returns the spec for a new key of the same type as pub
+func keySpecOf(pub crypto.PublicKey) (keySpec, error) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return keySpec{Type: KeyTypeRSA, RSABits: k.N.BitLen()}, nil
+	case *ecdsa.PublicKey:
+		return keySpec{Type: KeyTypeECDSA}, nil
+	case ed25519.PublicKey:
+		return keySpec{Type: KeyTypeED25519}, nil
+	default:
+		return keySpec{}, fmt.Errorf("unsupported public key type %T", pub)
+	}
+}
+
+// issue creates the certificate for key. If iss is nil, the certificate is
+// self-signed.
+//
+//nolint:whitespace // editor/linter issue
+func issue(
+	req certRequest,
+	key crypto.Signer,
+	iss *issuer,
+) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: req.CommonName},
+		DNSNames:     req.DNSNames,
+		IPAddresses:  req.IPs,
+		URIs:         req.URIs,
+		NotBefore:    now.Add(-time.Minute), // allow some clock skew
+		NotAfter:     now.Add(req.Validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  req.ExtKeyUsage,
+	}
+	if req.Organization != "" {
+		tmpl.Subject.Organization = []string{req.Organization}
+	}
+	if _, ok := key.Public().(*rsa.PublicKey); ok && !req.IsCA {
+		tmpl.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+	if req.IsCA {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	}
+	parent, parentKey := tmpl, key
+	if iss != nil {
+		parent, parentKey = iss.cert, iss.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, key.Public(), parentKey)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(der)
+}
+
+// requestOf returns the request to reissue c
+func requestOf(c *x509.Certificate) certRequest {
+	req := certRequest{
+		CommonName:  c.Subject.CommonName,
+		DNSNames:    c.DNSNames,
+		IPs:         c.IPAddresses,
+		URIs:        c.URIs,
+		IsCA:        c.IsCA,
+		ExtKeyUsage: c.ExtKeyUsage,
+		Validity:    c.NotAfter.Sub(c.NotBefore),
+	}
+	if len(c.Subject.Organization) > 0 {
+		req.Organization = c.Subject.Organization[0]
+	}
+	return req
+}
+
+// loadIssuer reads the CA certificate and its key
+func loadIssuer(certFile, keyFile string) (*issuer, error) {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load CA: %w", err)
+	}
+	if !pair.Leaf.IsCA {
+		return nil, fmt.Errorf("%s is not a CA certificate", certFile)
+	}
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported CA key")
+	}
+	return &issuer{cert: pair.Leaf, key: key}, nil
+}
+
+// chain returns c and the issuer certificates up to the root (excluded)
+func (iss *issuer) chain(c *x509.Certificate) []*x509.Certificate {
+	ret := []*x509.Certificate{c}
+	if iss != nil && !bytes.Equal(iss.cert.RawIssuer, iss.cert.RawSubject) {
+		ret = append(ret, iss.cert)
+	}
+	return ret
+}
+
+// writeCertAndKey writes the files <base>.<certExt> and <base>.key.
+// Both files are written to temporary files first and then renamed, the
+// certificate first. So watchers never see partial content and a TLSReloader
+// sees both new files within its reload delay.
+//
+//nolint:whitespace // editor/linter issue
+func writeCertAndKey(
+	base, certExt string,
+	chain []*x509.Certificate,
+	key crypto.Signer,
+) error {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	var certPEM bytes.Buffer
+	for _, c := range chain {
+		if err := pem.Encode(&certPEM,
+			&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw}); err != nil {
+
+			return err
+		}
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	certFile, keyFile := base+"."+certExt, base+".key"
+	certTmp, err := writeTemp(certFile, certPEM.Bytes(), 0o644)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(certTmp)
+	keyTmp, err := writeTemp(keyFile, keyPEM, 0o600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(keyTmp)
+	if err := os.Rename(certTmp, certFile); err != nil {
+		return err
+	}
+	return os.Rename(keyTmp, keyFile)
+}
+
+// writeTemp writes data to a temporary file next to name and returns its name
+func writeTemp(name string, data []byte, perm os.FileMode) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return "", err
+	}
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Chmod(perm)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// readCertificate returns the first certificate of the PEM file
+func readCertificate(name string) (*x509.Certificate, error) {
+	certs, err := readCertificates(name)
+	if err != nil {
+		return nil, err
+	}
+	return certs[0], nil
+}
+
+// readCertificates returns the certificates of the PEM file in file order
+func readCertificates(name string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := config.ParsePEMCertificates(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("%s: no certificate found", name)
+	}
+	return ret, nil
+}
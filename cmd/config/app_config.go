@@ -0,0 +1,219 @@
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/mpapenbr/otlpdemo/log"
+)
+
+type (
+	// AppConfig holds the settings of the executed command.
+	// It is created once by NewAppConfig and passed to the builders.
+	AppConfig struct {
+		TLS       TLSConfig
+		Server    ServerConfig
+		Telemetry TelemetryConfig
+		Log       LogConfig
+		DB        DBConfig
+	}
+	TLSConfig struct {
+		Insecure   bool     // connect to server without TLS
+		MinVersion string   // minimum TLS version (e.g., "TLS13")
+		SkipVerify bool     // skip TLS verification
+		Cert       string   // path to TLS certificate
+		Key        string   // path to TLS key
+		CAs        []string // path to TLS CA (to validate server certificate)
+		ClientCAs  []string // path to TLS CA (to validate client certificate)
+		ClientAuth string   // TLS client authentication mode
+		// warn if a certificate expires within one of these durations
+		WarnThresholds []time.Duration
+	}
+	ServerConfig struct {
+		Address          string // address to listen on/connect to
+		GRPCLogVerbosity int    // verbosity for gRPC internal logging
+		AuthzPolicy      string // policy file to authorize clients by certificate
+		DebugAddress     string // address of the debug endpoints (empty: disabled)
+	}
+	TelemetryConfig struct {
+		Enabled  bool
+		Endpoint string
+		Output   string // output for otel-logger (stdout, grpc)
+	}
+	LogConfig struct {
+		File string // log config file
+		// the levels are only set if given explicitly by flag, env var or config
+		// file. Otherwise the levels of the log config are used.
+		Level     string
+		OtelLevel string // threshold for OTLP logs (default: Level)
+	}
+	appConfigKey string
+)
+
+// NewAppConfig creates the config from the flags of cmd. The values of the
+// config file and env vars are already applied to the flags (see initConfig).
+// Persistent flags (e.g. --tls-cert of web) hold the shared settings, local
+// flags the settings of the command (e.g. --addr, --tls-cert of db).
+func NewAppConfig(cmd *cobra.Command) (*AppConfig, error) {
+	shared := pflag.NewFlagSet("shared", pflag.ContinueOnError)
+	shared.AddFlagSet(cmd.InheritedFlags())
+	shared.AddFlagSet(cmd.PersistentFlags())
+	s := flagReader{flags: shared}
+	l := flagReader{flags: cmd.LocalNonPersistentFlags()}
+
+	c := &AppConfig{}
+	s.boolean("insecure", &c.TLS.Insecure)
+	s.str("tls-min-version", &c.TLS.MinVersion)
+	s.boolean("tls-skip-verify", &c.TLS.SkipVerify)
+	s.str("tls-cert", &c.TLS.Cert)
+	s.str("tls-key", &c.TLS.Key)
+	s.strSlice("tls-ca", &c.TLS.CAs)
+	s.strSlice("tls-client-ca", &c.TLS.ClientCAs)
+	s.str("tls-client-auth", &c.TLS.ClientAuth)
+	s.durationSlice("tls-cert-warn", &c.TLS.WarnThresholds)
+
+	l.str("addr", &c.Server.Address)
+	l.str("authz-policy", &c.Server.AuthzPolicy)
+	l.str("debug-addr", &c.Server.DebugAddress)
+	s.integer("grpc-log-verbosity", &c.Server.GRPCLogVerbosity)
+
+	s.boolean("enable-telemetry", &c.Telemetry.Enabled)
+	s.str("telemetry-endpoint", &c.Telemetry.Endpoint)
+	s.str("otel-output", &c.Telemetry.Output)
+
+	s.str("log-config", &c.Log.File)
+	if shared.Changed("log-level") {
+		s.str("log-level", &c.Log.Level)
+	}
+	if shared.Changed("otel-log-level") {
+		s.str("otel-log-level", &c.Log.OtelLevel)
+	}
+
+	l.str("host", &c.DB.Host)
+	l.integer("port", &c.DB.Port)
+	l.str("database", &c.DB.Database)
+	l.str("user", &c.DB.StaticSecrets.User)
+	l.str("password", &c.DB.StaticSecrets.Password)
+	l.str("secrets-file", &c.DB.SecretsFile)
+	l.str("sslmode", &c.DB.SSLMode)
+	l.str("tls-cert", &c.DB.TLSCert)
+	l.str("tls-key", &c.DB.TLSKey)
+	l.str("tls-ca", &c.DB.TLSCA)
+
+	return c, errors.Join(s.errs, l.errs)
+}
+
+// Validate reports invalid values and contradicting settings
+func (c *AppConfig) Validate() error {
+	var errs []error
+	for _, lvl := range []string{c.Log.Level, c.Log.OtelLevel} {
+		if _, err := log.ParseLevel(lvl); lvl != "" && err != nil {
+			errs = append(errs, fmt.Errorf("invalid log level %q", lvl))
+		}
+	}
+	errs = append(errs, c.TLS.validate()...)
+	if c.Server.AuthzPolicy != "" && !c.TLS.verifiesClients() {
+		errs = append(errs, errors.New("--authz-policy requires --tls-client-auth "+
+			"verify-if-given or require-and-verify"))
+	}
+	return errors.Join(errs...)
+}
+
+func (c *TLSConfig) validate() []error {
+	var errs []error
+	if c.Insecure {
+		if c.Cert != "" || c.Key != "" {
+			errs = append(errs,
+				errors.New("--insecure can't be combined with --tls-cert/--tls-key"))
+		}
+		if c.ClientAuth != "" {
+			errs = append(errs,
+				errors.New("--insecure can't be combined with --tls-client-auth"))
+		}
+		return errs
+	}
+	if (c.Cert == "") != (c.Key == "") {
+		errs = append(errs, errors.New("--tls-cert and --tls-key must be used together"))
+	}
+	if c.MinVersion != "" {
+		if _, err := ParseTLSVersion(c.MinVersion); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.ClientAuth != "" {
+		if _, err := ParseClientAuth(c.ClientAuth); err != nil {
+			errs = append(errs, err)
+		} else if c.verifiesClients() && len(c.ClientCAs) == 0 {
+			// require only asks for a certificate, there is nothing to verify against
+			errs = append(errs, fmt.Errorf("--tls-client-auth %s requires --tls-client-ca",
+				c.ClientAuth))
+		}
+	}
+	return errs
+}
+
+// verifiesClients reports if client certificates are verified against the
+// client CAs
+func (c *TLSConfig) verifiesClients() bool {
+	mode, err := ParseClientAuth(c.ClientAuth)
+	return !c.Insecure && err == nil &&
+		(mode == tls.VerifyClientCertIfGiven || mode == tls.RequireAndVerifyClientCert)
+}
+
+func AddToContext(ctx context.Context, cfg *AppConfig) context.Context {
+	return context.WithValue(ctx, appConfigKey("appConfig"), cfg)
+}
+
+func GetFromContext(ctx context.Context) *AppConfig {
+	if ctx == nil {
+		return nil
+	}
+	if cfg, ok := ctx.Value(appConfigKey("appConfig")).(*AppConfig); ok {
+		return cfg
+	}
+	return nil
+}
+
+// flagReader reads the values of existing flags, missing flags are ignored
+type flagReader struct {
+	flags *pflag.FlagSet
+	errs  error
+}
+
+func readFlag[T any](r *flagReader, name string, get func(string) (T, error), dst *T) {
+	if r.flags.Lookup(name) == nil {
+		return
+	}
+	v, err := get(name)
+	if err != nil {
+		r.errs = errors.Join(r.errs, err)
+		return
+	}
+	*dst = v
+}
+
+func (r *flagReader) str(name string, dst *string) {
+	readFlag(r, name, r.flags.GetString, dst)
+}
+
+func (r *flagReader) boolean(name string, dst *bool) {
+	readFlag(r, name, r.flags.GetBool, dst)
+}
+
+func (r *flagReader) integer(name string, dst *int) {
+	readFlag(r, name, r.flags.GetInt, dst)
+}
+
+func (r *flagReader) strSlice(name string, dst *[]string) {
+	readFlag(r, name, r.flags.GetStringSlice, dst)
+}
+
+func (r *flagReader) durationSlice(name string, dst *[]time.Duration) {
+	readFlag(r, name, r.flags.GetDurationSlice, dst)
+}
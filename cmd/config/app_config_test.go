@@ -0,0 +1,27 @@
+package config
+
+import "testing"
+
+func TestTLSConfigValidateClientAuth(t *testing.T) {
+	tests := []struct {
+		mode      string
+		clientCAs []string
+		wantErr   bool
+	}{
+		{"none", nil, false},
+		{"request", nil, false},
+		{"require", nil, false},
+		{"verify-if-given", nil, true},
+		{"verify-if-given", []string{"ca.pem"}, false},
+		{"require-and-verify", nil, true},
+		{"require-and-verify", []string{"ca.pem"}, false},
+		{"unknown", []string{"ca.pem"}, true},
+	}
+	for _, tt := range tests {
+		cfg := TLSConfig{ClientAuth: tt.mode, ClientCAs: tt.clientCAs}
+		if errs := cfg.validate(); (len(errs) > 0) != tt.wantErr {
+			t.Errorf("%s (client CAs %v): errs = %v, wantErr %t",
+				tt.mode, tt.clientCAs, errs, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/mpapenbr/otlpdemo/log"
+)
+
+// usage of a certificate in the inventory
+const (
+	CertUsageServer   = "server"    // leaf presented by a server
+	CertUsageClient   = "client"    // leaf presented by a client
+	CertUsageCA       = "ca"        // CA to verify the server certificate
+	CertUsageClientCA = "client-ca" // CA to verify client certificates
+)
+
+type (
+	// CertInventory keeps track of all loaded certificates.
+	// Certificates are registered per source (file) and usage, registering the
+	// same source and usage again replaces the certificates (e.g. on rotation).
+	CertInventory struct {
+		mu         sync.Mutex
+		entries    map[certKey][]*x509.Certificate
+		warned     map[string]time.Duration // fingerprint -> smallest threshold warned
+		thresholds []time.Duration          // descending
+		gauge      metric.Registration      // callback of tls.cert.expiry_seconds
+		startOnce  sync.Once
+		closeOnce  sync.Once
+		done       chan struct{}
+	}
+	certKey struct {
+		source string
+		usage  string
+	}
+	// LoadedCerts holds the certificates loaded from a file (source)
+	LoadedCerts struct {
+		Source string
+		Usage  string
+		Certs  []*x509.Certificate
+	}
+)
+
+// Certs is the inventory of all certificates loaded by this process
+var Certs = NewCertInventory()
+
+func NewCertInventory() *CertInventory {
+	return &CertInventory{
+		entries: map[certKey][]*x509.Certificate{},
+		warned:  map[string]time.Duration{},
+		done:    make(chan struct{}),
+	}
+}
+
+// SetWarnThresholds configures when warnings about expiring certificates
+// are logged. A warning is logged once per certificate and threshold.
+func (i *CertInventory) SetWarnThresholds(thresholds []time.Duration) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.thresholds = slices.Clone(thresholds)
+	slices.SortFunc(i.thresholds, func(a, b time.Duration) int {
+		return int(b - a)
+	})
+}
+
+// Register adds or replaces the certificates of source with the given usage.
+// The first call registers the gauge tls.cert.expiry_seconds and starts
+// a periodic check of the expiry until Close is called.
+func (i *CertInventory) Register(source, usage string, certs ...*x509.Certificate) {
+	i.startOnce.Do(func() {
+		if err := i.registerGauge(); err != nil {
+			log.Warn("could not register cert expiry gauge", log.ErrorField(err))
+		}
+		go i.checkPeriodically(time.Hour)
+	})
+	i.mu.Lock()
+	i.entries[certKey{source: source, usage: usage}] = slices.Clone(certs)
+	i.mu.Unlock()
+	for _, c := range certs {
+		log.Debug("registered certificate",
+			log.String("source", source),
+			log.String("usage", usage),
+			log.String("subject", c.Subject.String()),
+			log.Time("notAfter", c.NotAfter))
+	}
+	i.CheckExpiry()
+}
+
+// RegisterPEM registers all certificates contained in the PEM data
+func (i *CertInventory) RegisterPEM(source, usage string, data []byte) {
+	certs, err := ParsePEMCertificates(data)
+	if err != nil {
+		log.Warn("could not parse certificate",
+			log.String("source", source), log.ErrorField(err))
+	}
+	i.Register(source, usage, certs...)
+}
+
+// RegisterFile registers all certificates of a PEM file
+func (i *CertInventory) RegisterFile(source, usage string) {
+	data, err := os.ReadFile(source)
+	if err != nil {
+		log.Warn("could not read certificate file",
+			log.String("source", source), log.ErrorField(err))
+		return
+	}
+	i.RegisterPEM(source, usage, data)
+}
+
+// CheckExpiry logs a warning for each certificate that crossed a threshold
+// since the last check and an error for expired certificates.
+func (i *CertInventory) CheckExpiry() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	now := time.Now()
+	for key, certs := range i.entries {
+		for _, c := range certs {
+			remaining := c.NotAfter.Sub(now)
+			fp := Fingerprint(c)
+			var crossed time.Duration
+			for _, t := range i.thresholds {
+				if remaining <= t {
+					crossed = t
+				}
+			}
+			if crossed == 0 && remaining > 0 {
+				continue
+			}
+			if remaining <= 0 {
+				crossed = -1 // expired
+			}
+			if last, ok := i.warned[fp]; ok && last <= crossed {
+				continue
+			}
+			i.warned[fp] = crossed
+			fields := []log.Field{
+				log.String("source", key.source),
+				log.String("usage", key.usage),
+				log.String("subject", c.Subject.String()),
+				log.String("serial", c.SerialNumber.String()),
+				log.Time("notAfter", c.NotAfter),
+			}
+			if remaining <= 0 {
+				log.Error("certificate expired", fields...)
+			} else {
+				log.Warn("certificate expires soon", append(fields,
+					log.Duration("remaining", remaining.Round(time.Second)),
+					log.Duration("threshold", crossed))...)
+			}
+		}
+	}
+}
+
+// Close stops the periodic check and removes the certificates from the
+// gauge. The periodic check isn't started by later registrations.
+func (i *CertInventory) Close() error {
+	i.startOnce.Do(func() {})
+	i.closeOnce.Do(func() { close(i.done) })
+	i.mu.Lock()
+	gauge := i.gauge
+	i.gauge = nil
+	i.mu.Unlock()
+	if gauge != nil {
+		return gauge.Unregister()
+	}
+	return nil
+}
+
+func (i *CertInventory) checkPeriodically(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			i.CheckExpiry()
+		case <-i.done:
+			return
+		}
+	}
+}
+
+func (i *CertInventory) registerGauge() error {
+	meter := otel.Meter("otlpdemo/tls")
+	gauge, err := meter.Float64ObservableGauge("tls.cert.expiry_seconds",
+		metric.WithDescription("Time until the certificate expires"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return err
+	}
+	reg, err := meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		i.mu.Lock()
+		defer i.mu.Unlock()
+		now := time.Now()
+		for key, certs := range i.entries {
+			for _, c := range certs {
+				o.ObserveFloat64(gauge, c.NotAfter.Sub(now).Seconds(),
+					metric.WithAttributes(
+						attribute.String("subject", c.Subject.String()),
+						attribute.String("issuer", c.Issuer.String()),
+						attribute.String("serial", c.SerialNumber.String()),
+						attribute.String("source", key.source),
+						attribute.String("usage", key.usage)))
+			}
+		}
+		return nil
+	}, gauge)
+	if err != nil {
+		return err
+	}
+	i.mu.Lock()
+	i.gauge = reg
+	i.mu.Unlock()
+	return nil
+}
+
+// Fingerprint returns the SHA-256 fingerprint of the certificate (hex)
+func Fingerprint(c *x509.Certificate) string {
+	sum := sha256.Sum256(c.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// ParsePEMCertificates returns the certificates of data in file order.
+// Other blocks are ignored. Certificates which could not be parsed are
+// skipped, their errors are returned.
+func ParsePEMCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	var errs []error
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		certs = append(certs, c)
+	}
+	return certs, errors.Join(errs...)
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// expiryUsages returns the usages of the expiry observations of source
+func expiryUsages(t *testing.T, source string) []string {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := testMetricReader().Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	var ret []string
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			gauge, ok := m.Data.(metricdata.Gauge[float64])
+			if m.Name != "tls.cert.expiry_seconds" || !ok {
+				continue
+			}
+			for _, dp := range gauge.DataPoints {
+				if s, _ := dp.Attributes.Value("source"); s.AsString() == source {
+					usage, _ := dp.Attributes.Value("usage")
+					ret = append(ret, usage.AsString())
+				}
+			}
+		}
+	}
+	slices.Sort(ret)
+	return ret
+}
+
+func TestCertInventoryGauge(t *testing.T) {
+	testMetricReader() // the gauge is registered with the test provider
+	valid := time.Now().Add(time.Hour)
+	ca := newTestCert(t, "ca", nil, valid)
+	leaf := newTestCert(t, "localhost", ca, valid)
+	source := filepath.Join(t.TempDir(), "server.crt")
+
+	inv := NewCertInventory()
+	inv.Register(source, CertUsageServer, leaf.cert, ca.cert)
+	inv.Register(source, CertUsageCA, ca.cert)
+	if got := expiryUsages(t, source); !slices.Equal(got,
+		[]string{CertUsageCA, CertUsageServer, CertUsageServer}) {
+
+		t.Errorf("usages of %s = %v", source, got)
+	}
+
+	if err := inv.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := expiryUsages(t, source); len(got) != 0 {
+		t.Errorf("observed after Close: %v", got)
+	}
+	select {
+	case <-inv.done:
+	default:
+		t.Error("periodic check not stopped")
+	}
+	if err := inv.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
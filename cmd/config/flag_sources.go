@@ -0,0 +1,92 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// prefix of the env vars for flags
+const EnvPrefix = "OTLPDEMO_"
+
+// kinds of value sources
+const (
+	SourceDefault = "default"
+	SourceFlag    = "flag"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+)
+
+// FlagSource describes where the value of a flag comes from.
+// Name is the env var name or the config file.
+type FlagSource struct {
+	Kind string
+	Name string
+}
+
+// sources of values applied from config file or env vars
+var flagSources sync.Map // *pflag.Flag -> FlagSource
+
+func (s FlagSource) String() string {
+	if s.Name == "" {
+		return s.Kind
+	}
+	return s.Kind + " " + s.Name
+}
+
+// SetFlagSource records the source of a value applied to f from a config
+// file or env var. envVar is the variable bound to f, it is preferred if set.
+func SetFlagSource(f *pflag.Flag, envVar, configFile string) {
+	if _, ok := os.LookupEnv(envVar); ok {
+		flagSources.Store(f, FlagSource{Kind: SourceEnv, Name: envVar})
+	} else {
+		flagSources.Store(f, FlagSource{Kind: SourceFile, Name: configFile})
+	}
+}
+
+// GetFlagSource returns the source of the current value of f
+func GetFlagSource(f *pflag.Flag) FlagSource {
+	if !f.Changed {
+		return FlagSource{Kind: SourceDefault}
+	}
+	if s, ok := flagSources.Load(f); ok {
+		//nolint:errcheck // only sources are stored
+		return s.(FlagSource)
+	}
+	return FlagSource{Kind: SourceFlag}
+}
+
+// FlagKey returns the config key of the flag name as seen by cmd.
+// The key is the path of the command defining the flag (without the root
+// command) followed by the flag name, e.g. web.grpcserver.addr or web.tls-cert
+// for the persistent flag of web. Flags of the root command use their name.
+// It returns "" if the flag is unknown.
+func FlagKey(cmd *cobra.Command, name string) string {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.PersistentFlags().Lookup(name) != nil ||
+			(c == cmd && c.LocalNonPersistentFlags().Lookup(name) != nil) {
+
+			return strings.Join(append(commandPath(c), name), ".")
+		}
+	}
+	return ""
+}
+
+// FlagEnvVar returns the env var for the config key,
+// e.g. OTLPDEMO_WEB_GRPCSERVER_ADDR for web.grpcserver.addr
+func FlagEnvVar(key string) string {
+	r := strings.NewReplacer(".", "_", "-", "_")
+	return EnvPrefix + strings.ToUpper(r.Replace(key))
+}
+
+// commandPath returns the names of the commands from the root (excluded) to cmd
+func commandPath(cmd *cobra.Command) []string {
+	var ret []string
+	for c := cmd; c.HasParent(); c = c.Parent() {
+		ret = append([]string{c.Name()}, ret...)
+	}
+	return ret
+}
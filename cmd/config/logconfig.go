@@ -0,0 +1,59 @@
+package config
+
+import (
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"github.com/mpapenbr/otlpdemo/log"
+)
+
+const (
+	// key of the log config within the main config file
+	LogConfigKey = "log"
+	// environment variables with this prefix override the log config
+	LogEnvPrefix = "OTLPDEMO_LOG_"
+)
+
+// these env vars belong to the flags --log-level and --log-config
+var logFlagEnvVars = []string{"OTLPDEMO_LOG_LEVEL", "OTLPDEMO_LOG_CONFIG"}
+
+// BuildLogConfig assembles the log config from these sources,
+// later sources override earlier ones:
+//   - defaults
+//   - the section "log" of the main config file
+//   - the file given by --log-config
+//   - environment variables OTLPDEMO_LOG_* (see log.Config.ApplyEnv)
+//
+// The levels of conf (--log-level and --otel-log-level) override the levels
+// of the result.
+func BuildLogConfig(conf LogConfig) (*log.Config, error) {
+	mainFile := viper.ConfigFileUsed()
+	hasSection := mainFile != "" && viper.IsSet(LogConfigKey)
+
+	cfg := log.DefaultDevConfig()
+	if hasSection || conf.File != "" {
+		// the files are based on the production config like log.LoadConfig
+		cfg = log.DefaultProdConfig()
+	}
+	if hasSection {
+		if err := cfg.MergeFile(mainFile, LogConfigKey); err != nil {
+			return nil, err
+		}
+	}
+	if conf.File != "" {
+		if err := cfg.MergeFile(conf.File, ""); err != nil {
+			return nil, err
+		}
+	}
+	environ := slices.DeleteFunc(os.Environ(), func(kv string) bool {
+		name, _, _ := strings.Cut(kv, "=")
+		return slices.Contains(logFlagEnvVars, strings.ToUpper(name))
+	})
+	if err := cfg.ApplyEnv(LogEnvPrefix, environ); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
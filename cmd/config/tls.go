@@ -1,6 +1,7 @@
 package config
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
@@ -15,37 +16,61 @@ import (
 
 type TLSConfigOption func(*tls.Config)
 
-func BuildServerTLSConfig() (*tls.Config, error) {
-	if Insecure {
+// BuildServerTLSConfig creates the server config. The certificates are
+// reloaded on changes until ctx is done. ClientAuth of the returned config
+// is the configured mode, the handshake uses the config of GetConfigForClient.
+// Use it with NewTLSListener or NewHandshakeCredentials to record the
+// handshakes.
+func BuildServerTLSConfig(ctx context.Context, conf TLSConfig) (*tls.Config, error) {
+	if conf.Insecure {
 		log.Debug("using insecure mode. no TLS")
 		return nil, nil
 	} else {
-		reloader, err := NewTLSReloader()
+		reloader, err := NewTLSReloader(conf)
 		if err != nil {
 			log.Error("error creating TLS reloader", log.ErrorField(err))
 			return nil, err
 		}
-		go reloader.watch()
+		reloader.Start(ctx)
 		return &tls.Config{
 			MinVersion:         tls.VersionTLS13,
+			ClientAuth:         reloader.current().ClientAuth,
 			GetConfigForClient: reloader.GetConfigForClient,
 		}, nil
 	}
 }
 
-//nolint:nestif // false positive
-func BuildClientTLSConfig(opts ...TLSConfigOption) (*tls.Config, error) {
-	if Insecure {
+// BuildClientTLSConfig creates the client config. The client certificate
+// and the CAs are reloaded on changes until ctx is done.
+//
+//nolint:nestif,whitespace // false positive, editor/linter issue
+func BuildClientTLSConfig(
+	ctx context.Context,
+	conf TLSConfig,
+	opts ...TLSConfigOption,
+) (*tls.Config, error) {
+	if conf.Insecure {
 		log.Debug("using insecure mode. no TLS")
 		return nil, nil
 	} else {
-		return buildTLSFromConfig(opts...)
+		reloader, err := NewClientTLSReloader(conf, opts...)
+		if err != nil {
+			log.Error("error creating TLS reloader", log.ErrorField(err))
+			return nil, err
+		}
+		reloader.Start(ctx)
+		return reloader.ClientConfig(), nil
 	}
 }
 
 // used for gRPC
-func BuildTransportCredentials() (credentials.TransportCredentials, error) {
-	myTLS, err := BuildServerTLSConfig()
+//
+//nolint:whitespace // editor/linter issue
+func BuildTransportCredentials(
+	ctx context.Context,
+	conf TLSConfig,
+) (credentials.TransportCredentials, error) {
+	myTLS, err := BuildServerTLSConfig(ctx, conf)
 	if err != nil {
 		return nil, err
 	}
@@ -53,7 +78,7 @@ func BuildTransportCredentials() (credentials.TransportCredentials, error) {
 		return insecure.NewCredentials(), nil
 	} else {
 		log.Debug("TLS configured")
-		return credentials.NewTLS(myTLS), nil
+		return NewHandshakeCredentials(myTLS), nil
 	}
 }
 
@@ -74,6 +99,24 @@ func ParseClientAuth(mode string) (tls.ClientAuthType, error) {
 	}
 }
 
+// ClientAuthName returns the name of mode as used by ParseClientAuth
+func ClientAuthName(mode tls.ClientAuthType) string {
+	switch mode {
+	case tls.NoClientCert:
+		return "none"
+	case tls.RequestClientCert:
+		return "request"
+	case tls.RequireAnyClientCert:
+		return "require"
+	case tls.VerifyClientCertIfGiven:
+		return "verify-if-given"
+	case tls.RequireAndVerifyClientCert:
+		return "require-and-verify"
+	default:
+		return mode.String()
+	}
+}
+
 func ParseTLSVersion(mode string) (uint16, error) {
 	switch strings.ToLower(mode) {
 	case "tls13":
@@ -85,67 +128,99 @@ func ParseTLSVersion(mode string) (uint16, error) {
 	}
 }
 
-//nolint:funlen // by design
-func buildTLSFromConfig(opts ...TLSConfigOption) (*tls.Config, error) {
+// LoadTLSConfig loads the files of conf like the TLS builders do.
+// The returned certificates are grouped by file and usage.
+//
+//nolint:whitespace // editor/linter issue
+func LoadTLSConfig(
+	conf TLSConfig,
+	leafUsage string,
+) (*tls.Config, []LoadedCerts, error) {
+	return buildTLSFromConfig(conf, leafUsage)
+}
+
+// buildTLSFromConfig also returns the loaded certificates. They are meant to
+// be registered in the inventory once the config is accepted.
+// leafUsage is the usage of the certificate given by conf.Cert (server or client).
+//
+//nolint:funlen,whitespace // by design, editor/linter issue
+func buildTLSFromConfig(
+	conf TLSConfig,
+	leafUsage string,
+	opts ...TLSConfigOption,
+) (*tls.Config, []LoadedCerts, error) {
+	var loaded []LoadedCerts
 	tlsConfig := &tls.Config{
 		MinVersion: tls.VersionTLS13,
 	}
-	if minVersion, err := ParseTLSVersion(TLSMinVersion); err == nil {
+	if minVersion, err := ParseTLSVersion(conf.MinVersion); err == nil {
 		tlsConfig.MinVersion = minVersion
 	} else {
-		return nil, err
+		return nil, nil, err
 	}
-	if TLSCert != "" && TLSKey != "" {
+	if conf.Cert != "" && conf.Key != "" {
 		log.Debug("cert and key provided")
-		cert, err := tls.LoadX509KeyPair(TLSCert, TLSKey)
+		cert, err := tls.LoadX509KeyPair(conf.Cert, conf.Key)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		tlsConfig.Certificates = []tls.Certificate{cert}
+		loaded = append(loaded, LoadedCerts{conf.Cert, leafUsage,
+			[]*x509.Certificate{cert.Leaf}})
 	}
-	if len(TLSCAs) > 0 {
+	if len(conf.CAs) > 0 {
 		log.Debug("ca provided")
 		caCertPool := x509.NewCertPool()
-		for _, ca := range TLSCAs {
+		for _, ca := range conf.CAs {
 			log.Debug("loading CA", log.String("ca", ca))
 			caCert, err := os.ReadFile(ca)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			if ok := caCertPool.AppendCertsFromPEM(caCert); !ok {
-				return nil, fmt.Errorf("failed to append server certificate")
+				return nil, nil, fmt.Errorf("failed to append server certificate")
+			}
+			certs, err := ParsePEMCertificates(caCert)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", ca, err)
 			}
+			loaded = append(loaded, LoadedCerts{ca, CertUsageCA, certs})
 		}
 		// this is used on the client side to verify the server certificate
 		tlsConfig.RootCAs = caCertPool
 	}
-	if len(TLSClientCAs) > 0 {
+	if len(conf.ClientCAs) > 0 {
 		log.Debug("client ca provided")
 		caCertPool := x509.NewCertPool()
-		for _, ca := range TLSClientCAs {
+		for _, ca := range conf.ClientCAs {
 			log.Debug("loading client CA", log.String("ca", ca))
 			caCert, err := os.ReadFile(ca)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			if ok := caCertPool.AppendCertsFromPEM(caCert); !ok {
-				return nil, fmt.Errorf("failed to append client certificate")
+				return nil, nil, fmt.Errorf("failed to append client certificate")
+			}
+			certs, err := ParsePEMCertificates(caCert)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", ca, err)
 			}
+			loaded = append(loaded, LoadedCerts{ca, CertUsageClientCA, certs})
 		}
 		// this is used on the server side to verify the client certificate
 		tlsConfig.ClientCAs = caCertPool
 	}
-	if TLSClientAuth != "" {
+	if conf.ClientAuth != "" {
 		log.Debug("clientAuth provided")
 		var clientAuth tls.ClientAuthType
-		clientAuth, err := ParseClientAuth(TLSClientAuth)
+		clientAuth, err := ParseClientAuth(conf.ClientAuth)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		tlsConfig.ClientAuth = clientAuth
 	}
 
-	if TLSSkipVerify {
+	if conf.SkipVerify {
 		log.Debug("skipVerify enabled")
 		tlsConfig.InsecureSkipVerify = true
 	}
@@ -153,5 +228,5 @@ func buildTLSFromConfig(opts ...TLSConfigOption) (*tls.Config, error) {
 	for _, opt := range opts {
 		opt(tlsConfig)
 	}
-	return tlsConfig, nil
+	return tlsConfig, loaded, nil
 }
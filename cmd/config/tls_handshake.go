@@ -0,0 +1,364 @@
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/mpapenbr/otlpdemo/log"
+)
+
+// reasons of failed handshakes
+const (
+	HandshakeReasonUnknownCA = "unknown_ca"
+	HandshakeReasonExpired   = "expired" // also not yet valid
+	HandshakeReasonNoCert    = "no_cert"
+	HandshakeReasonBadSAN    = "bad_san"
+	HandshakeReasonOther     = "other"
+)
+
+// crypto/tls doesn't export this error
+const noClientCertMsg = "client didn't provide a certificate"
+
+type (
+	handshakeMetrics struct {
+		count    metric.Int64Counter
+		duration metric.Float64Histogram
+	}
+	// handshake holds what is known about a handshake when it is recorded
+	handshake struct {
+		usage      string // server or client
+		start      time.Time
+		clientAuth string // only known on the server side
+		state      tls.ConnectionState
+		err        error
+	}
+)
+
+// the metrics are created on first use
+var handshakeInstruments = sync.OnceValue(func() handshakeMetrics {
+	meter := otel.Meter("otlpdemo/tls")
+	var m handshakeMetrics
+	var err error
+	m.count, err = meter.Int64Counter("tls.handshakes",
+		metric.WithDescription("Number of TLS handshakes by outcome"),
+		metric.WithUnit("{handshake}"))
+	if err != nil {
+		log.Warn("could not create TLS handshake counter", log.ErrorField(err))
+	}
+	m.duration, err = meter.Float64Histogram("tls.handshake.duration",
+		metric.WithDescription("Duration from ClientHello to the verification of the peer"),
+		metric.WithUnit("s"))
+	if err != nil {
+		log.Warn("could not create TLS handshake histogram", log.ErrorField(err))
+	}
+	return m
+})
+
+// handshakeTimeout limits the handshakes done by the TLS listener
+const handshakeTimeout = 10 * time.Second
+
+type (
+	// tlsListener does the TLS handshake of accepted connections in the
+	// background, so failed handshakes can be recorded with their error.
+	tlsListener struct {
+		net.Listener
+		config *tls.Config
+		conns  chan net.Conn
+		errs   chan error
+		done   chan struct{}
+		once   sync.Once
+	}
+	// handshakeCredentials records the handshakes of gRPC connections and
+	// verifies the server by the authority on the client side
+	handshakeCredentials struct {
+		credentials.TransportCredentials
+		config     *tls.Config
+		clientAuth string
+	}
+)
+
+// NewTLSListener returns a listener for TLS connections accepted by inner.
+// Accept returns only connections with a completed handshake (see
+// BuildServerTLSConfig for cfg), the handshakes are recorded.
+func NewTLSListener(inner net.Listener, cfg *tls.Config) net.Listener {
+	l := &tlsListener{
+		Listener: inner,
+		config:   cfg,
+		conns:    make(chan net.Conn),
+		errs:     make(chan error),
+		done:     make(chan struct{}),
+	}
+	go l.acceptLoop()
+	return l
+}
+
+func (l *tlsListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case err := <-l.errs:
+		return nil, err
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *tlsListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return l.Listener.Close()
+}
+
+func (l *tlsListener) acceptLoop() {
+	for {
+		raw, err := l.Listener.Accept()
+		if err != nil {
+			// the caller decides about retries (e.g. http.Server on temporary errors)
+			select {
+			case l.errs <- err:
+			case <-l.done:
+				return
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		go l.handshake(raw)
+	}
+}
+
+func (l *tlsListener) handshake(raw net.Conn) {
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+	conn := tls.Server(raw, l.config)
+	start := time.Now()
+	err := conn.HandshakeContext(ctx)
+	recordHandshake(ctx, handshake{
+		usage:      CertUsageServer,
+		start:      start,
+		clientAuth: ClientAuthName(l.config.ClientAuth),
+		state:      conn.ConnectionState(),
+		err:        err,
+	})
+	if err != nil {
+		conn.Close()
+		return
+	}
+	select {
+	case l.conns <- conn:
+	case <-l.done:
+		conn.Close()
+	}
+}
+
+// NewTLSDialer returns a dialer for http.Transport.DialTLSContext using cfg
+// (see BuildClientTLSConfig) for the connections. The handshakes are recorded.
+func NewTLSDialer(cfg *tls.Config) func(context.Context, string, string) (
+	net.Conn, error,
+) {
+	var d net.Dialer
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		raw, err := d.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		conn := tls.Client(raw, clientConfigFor(cfg, addr))
+		start := time.Now()
+		err = conn.HandshakeContext(ctx)
+		recordHandshake(ctx, handshake{
+			usage: CertUsageClient,
+			start: start,
+			state: conn.ConnectionState(),
+			err:   err,
+		})
+		if err != nil {
+			raw.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+}
+
+// clientConfigFor returns a copy of cfg for a connection to addr.
+// crypto/tls reports only the server name sent via SNI to VerifyConnection,
+// which excludes IP addresses. The copy reports the host instead, so the
+// verification of the server certificate can check its IP SANs.
+func clientConfigFor(cfg *tls.Config, addr string) *tls.Config {
+	ret := cfg.Clone()
+	if ret.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		ret.ServerName = host
+	}
+	if next := ret.VerifyConnection; next != nil {
+		serverName := ret.ServerName
+		ret.VerifyConnection = func(cs tls.ConnectionState) error {
+			cs.ServerName = serverName
+			return next(cs)
+		}
+	}
+	return ret
+}
+
+// NewHandshakeCredentials returns gRPC credentials for cfg recording the
+// handshakes. On the client side (see BuildClientTLSConfig) the authority is
+// used to verify the server, cfg of the server side see BuildServerTLSConfig.
+func NewHandshakeCredentials(cfg *tls.Config) credentials.TransportCredentials {
+	return &handshakeCredentials{
+		TransportCredentials: credentials.NewTLS(cfg),
+		config:               cfg,
+		clientAuth:           ClientAuthName(cfg.ClientAuth),
+	}
+}
+
+//nolint:whitespace // editor/linter issue
+func (c *handshakeCredentials) ClientHandshake(
+	ctx context.Context,
+	authority string,
+	raw net.Conn,
+) (net.Conn, credentials.AuthInfo, error) {
+	start := time.Now()
+	conn, info, err := credentials.NewTLS(clientConfigFor(c.config, authority)).
+		ClientHandshake(ctx, authority, raw)
+	recordHandshake(ctx, tlsInfoHandshake(CertUsageClient, start, "", info, err))
+	return conn, info, err
+}
+
+//nolint:whitespace // editor/linter issue
+func (c *handshakeCredentials) ServerHandshake(raw net.Conn) (
+	net.Conn, credentials.AuthInfo, error,
+) {
+	start := time.Now()
+	conn, info, err := c.TransportCredentials.ServerHandshake(raw)
+	recordHandshake(context.Background(),
+		tlsInfoHandshake(CertUsageServer, start, c.clientAuth, info, err))
+	return conn, info, err
+}
+
+// tlsInfoHandshake returns the handshake of a gRPC connection.
+// The state is only known for successful handshakes.
+//
+//nolint:whitespace // editor/linter issue
+func tlsInfoHandshake(
+	usage string,
+	start time.Time,
+	clientAuth string,
+	info credentials.AuthInfo,
+	err error,
+) handshake {
+	h := handshake{usage: usage, start: start, clientAuth: clientAuth, err: err}
+	if tlsInfo, ok := info.(credentials.TLSInfo); ok {
+		h.state = tlsInfo.State
+	}
+	return h
+}
+
+func (c *handshakeCredentials) Clone() credentials.TransportCredentials {
+	return &handshakeCredentials{
+		TransportCredentials: c.TransportCredentials.Clone(),
+		config:               c.config.Clone(),
+		clientAuth:           c.clientAuth,
+	}
+}
+
+// recordHandshake counts the handshake and records its duration (if the start
+// is known). Failures are logged with a summary of the peer certificate.
+func recordHandshake(ctx context.Context, h handshake) {
+	outcome := "success"
+	if h.err != nil {
+		outcome = "failure"
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("usage", h.usage),
+		attribute.String("tls.version", tls.VersionName(h.state.Version)),
+		attribute.String("tls.cipher", tls.CipherSuiteName(h.state.CipherSuite)),
+		attribute.String("outcome", outcome),
+	}
+	if h.clientAuth != "" {
+		attrs = append(attrs, attribute.String("tls.client_auth", h.clientAuth))
+	}
+	if h.err != nil {
+		reason := HandshakeReason(h.err)
+		attrs = append(attrs, attribute.String("reason", reason))
+		fields := []log.Field{
+			log.String("usage", h.usage),
+			log.String("reason", reason),
+			log.String("serverName", h.state.ServerName),
+			log.ErrorField(h.err),
+		}
+		peerCerts := h.state.PeerCertificates
+		// crypto/tls sets the peer certificates only if they are verified
+		var verifyErr *tls.CertificateVerificationError
+		if len(peerCerts) == 0 && errors.As(h.err, &verifyErr) {
+			peerCerts = verifyErr.UnverifiedCertificates
+		}
+		log.Debug("TLS handshake failed",
+			append(fields, peerCertFields(peerCerts)...)...)
+	}
+	m := handshakeInstruments()
+	opt := metric.WithAttributes(attrs...)
+	if m.count != nil {
+		m.count.Add(ctx, 1, opt)
+	}
+	if m.duration != nil && !h.start.IsZero() {
+		m.duration.Record(ctx, time.Since(h.start).Seconds(), opt)
+	}
+}
+
+// HandshakeReason returns the reason code of a failed verification
+func HandshakeReason(err error) string {
+	var unknownCA x509.UnknownAuthorityError
+	var invalid x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	switch {
+	case errors.Is(err, errNoServerCert), strings.Contains(err.Error(), noClientCertMsg):
+		return HandshakeReasonNoCert
+	case errors.As(err, &unknownCA):
+		return HandshakeReasonUnknownCA
+	case errors.As(err, &invalid) && invalid.Reason == x509.Expired:
+		return HandshakeReasonExpired
+	case errors.As(err, &hostname), errors.Is(err, errNoServerName):
+		return HandshakeReasonBadSAN
+	default:
+		return HandshakeReasonOther
+	}
+}
+
+// peerCertFields returns log fields describing the leaf of certs
+func peerCertFields(certs []*x509.Certificate) []log.Field {
+	if len(certs) == 0 {
+		return []log.Field{log.Bool("peerCert", false)}
+	}
+	c := certs[0]
+	ips := make([]string, 0, len(c.IPAddresses))
+	for _, ip := range c.IPAddresses {
+		ips = append(ips, ip.String())
+	}
+	uris := make([]string, 0, len(c.URIs))
+	for _, u := range c.URIs {
+		uris = append(uris, u.String())
+	}
+	return []log.Field{
+		log.String("peerSubject", c.Subject.String()),
+		log.String("peerIssuer", c.Issuer.String()),
+		log.Any("peerDNSNames", c.DNSNames),
+		log.Any("peerIPs", ips),
+		log.Any("peerURIs", uris),
+		log.Time("peerNotBefore", c.NotBefore),
+		log.Time("peerNotAfter", c.NotAfter),
+		log.String("peerFingerprint", Fingerprint(c)),
+	}
+}
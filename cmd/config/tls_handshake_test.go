@@ -0,0 +1,219 @@
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+type handshakeFixture struct {
+	server  *tls.Config // see BuildServerTLSConfig
+	roots   *x509.CertPool
+	client  *testCert // issued by the client CA of the server
+	unknown *testCert // issued by another CA
+}
+
+// the handshake instruments are created once, so the provider is set once
+var testMetricReader = sync.OnceValue(func() *sdkmetric.ManualReader {
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	return reader
+})
+
+// newHandshakeFixture creates a server config requiring verified client certs
+func newHandshakeFixture(t *testing.T) *handshakeFixture {
+	t.Helper()
+	dir := t.TempDir()
+	valid := time.Now().Add(time.Hour)
+	ca := newTestCert(t, "ca", nil, valid)
+	otherCA := newTestCert(t, "other-ca", nil, valid)
+	caFile, _ := ca.write(t, dir, "ca")
+	serverCert, serverKey := newTestCert(t, "localhost", ca, valid,
+		x509.ExtKeyUsageServerAuth).write(t, dir, "server")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	cfg, err := BuildServerTLSConfig(ctx, TLSConfig{
+		MinVersion: "TLS13",
+		Cert:       serverCert,
+		Key:        serverKey,
+		ClientCAs:  []string{caFile},
+		ClientAuth: "require-and-verify",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret := &handshakeFixture{
+		server:  cfg,
+		roots:   x509.NewCertPool(),
+		client:  newTestCert(t, "client", ca, valid, x509.ExtKeyUsageClientAuth),
+		unknown: newTestCert(t, "unknown", otherCA, valid, x509.ExtKeyUsageClientAuth),
+	}
+	ret.roots.AddCert(ca.cert)
+	return ret
+}
+
+// clientConfig returns a client config sending leaf (nil: no certificate)
+func (f *handshakeFixture) clientConfig(leaf *testCert) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS13,
+		RootCAs:    f.roots,
+		ServerName: "localhost",
+		// sent even if not issued by one of the CAs requested by the server
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (
+			*tls.Certificate, error,
+		) {
+			if leaf == nil {
+				return &tls.Certificate{}, nil
+			}
+			return &tls.Certificate{
+				Certificate: [][]byte{leaf.cert.Raw},
+				PrivateKey:  leaf.key,
+			}, nil
+		},
+	}
+}
+
+// handshakeCounts returns the recorded handshakes by outcome and reason
+func handshakeCounts(t *testing.T) map[string]int64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := testMetricReader().Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	ret := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if m.Name != "tls.handshakes" || !ok {
+				continue
+			}
+			for _, dp := range sum.DataPoints {
+				outcome, _ := dp.Attributes.Value("outcome")
+				reason, _ := dp.Attributes.Value("reason")
+				key := outcome.AsString()
+				if reason.Type() != attribute.INVALID {
+					key += "/" + reason.AsString()
+				}
+				ret[key] += dp.Value
+			}
+		}
+	}
+	return ret
+}
+
+// expectHandshakes waits until the handshakes recorded since before are want
+//
+//nolint:whitespace // editor/linter issue
+func expectHandshakes(
+	t *testing.T,
+	before map[string]int64,
+	want map[string]int64,
+) {
+	t.Helper()
+	var total int64
+	for _, v := range want {
+		total += v
+	}
+	got := map[string]int64{}
+	for range 50 { // the server records after the client got the alert
+		var n int64
+		for k, v := range handshakeCounts(t) {
+			got[k] = v - before[k]
+			n += got[k]
+		}
+		if n == total {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("handshakes %s = %d, want %d (all: %v)", k, got[k], v, got)
+		}
+	}
+}
+
+func TestTLSListenerRecordsHandshakes(t *testing.T) {
+	before := handshakeCounts(t)
+	f := newHandshakeFixture(t)
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis := NewTLSListener(inner, f.server)
+	defer lis.Close()
+	verifiedChains := make(chan int, 3)
+	go func() {
+		for {
+			c, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			//nolint:errcheck // only TLS connections are returned
+			verifiedChains <- len(c.(*tls.Conn).ConnectionState().VerifiedChains)
+			c.Close()
+		}
+	}()
+
+	for _, leaf := range []*testCert{f.client, nil, f.unknown} {
+		conn, err := tls.Dial("tcp", inner.Addr().String(), f.clientConfig(leaf))
+		if err != nil {
+			continue
+		}
+		// with TLS 1.3 the client learns about the rejection on the first read
+		_, _ = conn.Read(make([]byte, 1))
+		conn.Close()
+	}
+
+	select {
+	case n := <-verifiedChains:
+		if n == 0 {
+			t.Error("expected verified chains for the accepted connection")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no connection accepted")
+	}
+	expectHandshakes(t, before, map[string]int64{
+		"success":            1,
+		"failure/no_cert":    1,
+		"failure/unknown_ca": 1,
+	})
+}
+
+func TestHandshakeCredentialsRecordsHandshakes(t *testing.T) {
+	before := handshakeCounts(t)
+	f := newHandshakeFixture(t)
+	creds := NewHandshakeCredentials(f.server)
+	for _, leaf := range []*testCert{f.client, f.unknown} {
+		serverConn, clientConn := net.Pipe()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			if conn, _, err := creds.ServerHandshake(serverConn); err == nil {
+				conn.Close()
+			}
+		}()
+		cfg := f.clientConfig(leaf)
+		cfg.NextProtos = []string{"h2"} // required by the gRPC credentials
+		conn := tls.Client(clientConn, cfg)
+		if err := conn.Handshake(); err == nil {
+			_, _ = conn.Read(make([]byte, 1))
+		}
+		conn.Close()
+		<-done
+	}
+	expectHandshakes(t, before, map[string]int64{
+		"success":            1,
+		"failure/unknown_ca": 1,
+	})
+}
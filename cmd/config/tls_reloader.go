@@ -1,7 +1,10 @@
 package config
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"path/filepath"
 	"slices"
 	"sync"
@@ -12,26 +15,35 @@ import (
 	"github.com/mpapenbr/otlpdemo/log"
 )
 
+// TLSReloader watches the certificate files and reloads the TLS config
+// on changes. It is used on the server side via GetConfigForClient and on the
+// client side via GetClientCertificate and VerifyConnection.
 type TLSReloader struct {
 	certPath      string
 	keyPath       string
 	caPaths       []string
 	clientCAPaths []string
+	conf          TLSConfig
+	usage         string // usage of the leaf certificate (server or client)
+	opts          []TLSConfigOption
 
 	mu        sync.RWMutex
 	tlsConfig *tls.Config
+	status    TLSReloadStatus
 }
 
 //nolint:whitespace //editor/linter issue
-func NewTLSReloader() (
+func NewTLSReloader(conf TLSConfig) (
 	*TLSReloader,
 	error,
 ) {
 	r := &TLSReloader{
-		certPath:      TLSCert,
-		keyPath:       TLSKey,
-		caPaths:       TLSCAs,
-		clientCAPaths: TLSClientCAs,
+		certPath:      conf.Cert,
+		keyPath:       conf.Key,
+		caPaths:       conf.CAs,
+		clientCAPaths: conf.ClientCAs,
+		conf:          conf,
+		usage:         CertUsageServer,
 	}
 	if err := r.reload(); err != nil {
 		return nil, err
@@ -39,57 +51,206 @@ func NewTLSReloader() (
 	return r, nil
 }
 
-//nolint:funlen,nestif // by design
+// NewClientTLSReloader creates a reloader for the client side.
+// The opts are applied to each reloaded config.
+//
+//nolint:whitespace // editor/linter issue
+func NewClientTLSReloader(
+	conf TLSConfig,
+	opts ...TLSConfigOption,
+) (*TLSReloader, error) {
+	r := &TLSReloader{
+		certPath: conf.Cert,
+		keyPath:  conf.Key,
+		caPaths:  conf.CAs,
+		conf:     conf,
+		usage:    CertUsageClient,
+		opts:     opts,
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Start watches the certificate files until ctx is done.
+// While running, the reloader is included in TLSReloadStatuses.
+func (r *TLSReloader) Start(ctx context.Context) {
+	reloaders.Store(r, struct{}{})
+	go func() {
+		defer reloaders.Delete(r)
+		r.watch(ctx)
+	}()
+}
+
+// reload replaces the config only if the new one is valid (see
+// validateTLSConfig). Otherwise the last good config is kept.
 func (r *TLSReloader) reload() error {
-	newCfg, err := buildTLSFromConfig()
-	if err != nil {
-		return err
+	newCfg, loaded, err := buildTLSFromConfig(r.conf, r.usage, r.opts...)
+	if err == nil {
+		err = validateTLSConfig(newCfg, loaded, time.Now())
 	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.status.Usage = r.usage
+	if err != nil {
+		r.status.Failures++
+		r.status.LastError = err.Error()
+		r.status.LastErrorTime = time.Now()
+		recordReload(r.usage, err)
+		if r.tlsConfig != nil {
+			log.Warn("Keeping current TLS config",
+				log.String("usage", r.usage),
+				log.String("fingerprint", r.status.Fingerprint))
+		}
+		return err
+	}
 	r.tlsConfig = newCfg
-	log.Debug("Reloaded TLS config (server cert + multiple client CAs)")
+	r.status.Reloads++
+	r.status.LastReload = time.Now()
+	r.status.Fingerprint, r.status.NotAfter = "", time.Time{}
+	if len(newCfg.Certificates) > 0 {
+		r.status.Fingerprint = Fingerprint(newCfg.Certificates[0].Leaf)
+		r.status.NotAfter = newCfg.Certificates[0].Leaf.NotAfter
+	}
+	recordReload(r.usage, nil)
+	// the certificates are registered in the inventory again, so rotated
+	// certificates replace the old ones
+	for _, e := range loaded {
+		Certs.Register(e.Source, e.Usage, e.Certs...)
+	}
+	log.Debug("Reloaded TLS config",
+		log.String("usage", r.usage),
+		log.String("fingerprint", r.status.Fingerprint))
 	return nil
 }
 
-func (r *TLSReloader) GetConfigForClient(_ *tls.ClientHelloInfo) (*tls.Config, error) {
+// Status returns the current state of the reloader
+func (r *TLSReloader) Status() TLSReloadStatus {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
+	return r.status
+}
+
+func (r *TLSReloader) current() *tls.Config {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.tlsConfig
+}
+
+// GetConfigForClient returns the current config
+//
+//nolint:whitespace // editor/linter issue
+func (r *TLSReloader) GetConfigForClient(
+	_ *tls.ClientHelloInfo,
+) (*tls.Config, error) {
 	log.Debug("GetConfigForClient callback invoked")
-	return r.tlsConfig, nil
+	return r.current(), nil
 }
 
-//nolint:funlen // by design
-func (r *TLSReloader) watch() {
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		log.Fatal("watcher error:", log.ErrorField(err))
+// GetClientCertificate returns the current client certificate.
+// If none is configured, no certificate is sent to the server.
+//
+//nolint:whitespace // editor/linter issue
+func (r *TLSReloader) GetClientCertificate(
+	_ *tls.CertificateRequestInfo,
+) (*tls.Certificate, error) {
+	log.Debug("GetClientCertificate callback invoked")
+	if cfg := r.current(); len(cfg.Certificates) > 0 {
+		return &cfg.Certificates[0], nil
 	}
-	defer watcher.Close()
+	return &tls.Certificate{}, nil
+}
 
-	filesToWatch := []string{}
-	watchPaths := map[string]struct{}{
-		filepath.Dir(r.certPath): {},
-		filepath.Dir(r.keyPath):  {},
+// ClientConfig returns the config to be used by clients. Certificates and CAs
+// are taken from the current reloaded config on each handshake.
+// Dial with NewTLSDialer or NewHandshakeCredentials, they record the
+// handshakes and pass the target host to the verification of the server
+// certificate, IP addresses included.
+func (r *TLSReloader) ClientConfig() *tls.Config {
+	cfg := r.current().Clone()
+	cfg.Certificates = nil
+	cfg.GetClientCertificate = r.GetClientCertificate
+	verify := func(tls.ConnectionState) error { return nil }
+	if cfg.RootCAs != nil && !cfg.InsecureSkipVerify {
+		// the RootCAs of a config can't be replaced during its usage.
+		// The verification against the current CAs is done in VerifyConnection.
+		cfg.RootCAs = nil
+		cfg.InsecureSkipVerify = true
+		verify = r.verifyServer
 	}
-	filesToWatch = append(filesToWatch, r.certPath)
-	filesToWatch = append(filesToWatch, r.keyPath)
+	next := cfg.VerifyConnection
+	cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+		err := verify(cs)
+		if err == nil && next != nil {
+			err = next(cs)
+		}
+		return err
+	}
+	return cfg
+}
 
-	for _, caPath := range r.caPaths {
-		watchPaths[filepath.Dir(caPath)] = struct{}{}
-		filesToWatch = append(filesToWatch, caPath)
+var (
+	errNoServerCert = errors.New("no server certificate")
+	errNoServerName = errors.New("server name required to verify the server certificate")
+)
+
+// verifyServer does the verification of the server certificate like
+// crypto/tls does, using the current RootCAs. The server name is either the
+// one sent via SNI or the host set by clientConfigFor.
+func (r *TLSReloader) verifyServer(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errNoServerCert
+	}
+	if cs.ServerName == "" {
+		return errNoServerName
+	}
+	opts := x509.VerifyOptions{
+		DNSName:       cs.ServerName,
+		Roots:         r.current().RootCAs,
+		Intermediates: x509.NewCertPool(),
 	}
+	for _, c := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// reloadDelay is the time without changes of the watched files before they
+// are reloaded
+const reloadDelay = time.Second
 
-	for _, clientCAPath := range r.clientCAPaths {
-		watchPaths[filepath.Dir(clientCAPath)] = struct{}{}
-		filesToWatch = append(filesToWatch, clientCAPath)
+//nolint:funlen // by design
+func (r *TLSReloader) watch(ctx context.Context) {
+	filesToWatch := []string{}
+	watchPaths := map[string]struct{}{}
+	paths := append([]string{r.certPath, r.keyPath}, r.caPaths...)
+	for _, file := range append(paths, r.clientCAPaths...) {
+		if file == "" {
+			continue // e.g. a client with --tls-ca only
+		}
+		watchPaths[filepath.Dir(file)] = struct{}{}
+		filesToWatch = append(filesToWatch, file)
+	}
+	if len(filesToWatch) == 0 {
+		return
 	}
 
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Error("could not watch cert files", log.String("usage", r.usage),
+			log.ErrorField(err))
+		return
+	}
+	defer watcher.Close()
+
 	for dir := range watchPaths {
 		if err := watcher.Add(dir); err != nil {
-			log.Fatal("fsnotify watch error ",
+			log.Error("could not watch dir, changes are not reloaded",
 				log.String("dir", dir),
 				log.ErrorField(err))
+			continue
 		}
 		// check if link ..data exists in dir (e.g., when using cert-manager in k8s)
 		dataLink := filepath.Join(dir, "..data")
@@ -99,6 +260,9 @@ func (r *TLSReloader) watch() {
 			filesToWatch = append(filesToWatch, dataLink)
 		}
 	}
+	if len(watcher.WatchList()) == 0 {
+		return
+	}
 	// resolve symlinks
 	for _, file := range filesToWatch {
 		resolved, err := filepath.EvalSymlinks(file)
@@ -108,7 +272,9 @@ func (r *TLSReloader) watch() {
 				log.ErrorField(err))
 			continue
 		}
-		filesToWatch = append(filesToWatch, resolved)
+		if resolved != file {
+			filesToWatch = append(filesToWatch, resolved)
+		}
 	}
 
 	log.Debug("Watching filenames for changes",
@@ -121,22 +287,29 @@ func (r *TLSReloader) watch() {
 			log.Bool("result", ret))
 		return ret
 	}
-	var lastReload time.Time
+	// reload once the files didn't change for reloadDelay, so cert and key
+	// written one after another are reloaded together
+	debounce := time.NewTimer(reloadDelay)
+	debounce.Stop()
+	defer debounce.Stop()
 	for {
 		select {
+		case <-ctx.Done():
+			log.Debug("Stopped watching cert files", log.String("usage", r.usage))
+			return
 		case event, ok := <-watcher.Events:
 			if !ok {
 				return
 			}
 			log.Debug("event", log.Any("name", event))
-			if isCertFile(event.Name) && time.Since(lastReload) > time.Second {
-				lastReload = time.Now()
-				time.Sleep(time.Second) // debounce
-				log.Debug("Change in dir detected. reloading certs",
-					log.String("name", event.Name))
-				if err := r.reload(); err != nil {
-					log.Error("error reloading", log.ErrorField(err))
-				}
+			if isCertFile(event.Name) {
+				debounce.Reset(reloadDelay)
+			}
+		case <-debounce.C:
+			log.Debug("Change in dir detected. reloading certs",
+				log.String("usage", r.usage))
+			if err := r.reload(); err != nil {
+				log.Error("error reloading", log.ErrorField(err))
 			}
 
 		case err, ok := <-watcher.Errors:
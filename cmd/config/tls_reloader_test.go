@@ -0,0 +1,205 @@
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// startIPServer serves HTTPS with a certificate for 127.0.0.1 only.
+// It returns the address and the CA file for the clients.
+func startIPServer(t *testing.T) (addr, caFile string) {
+	t.Helper()
+	dir := t.TempDir()
+	valid := time.Now().Add(time.Hour)
+	ca := newTestCert(t, "ca", nil, valid)
+	caFile, _ = ca.write(t, dir, "ca")
+	certFile, keyFile := newTestCert(t, "127.0.0.1", ca, valid,
+		x509.ExtKeyUsageServerAuth).write(t, dir, "server")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	cfg, err := BuildServerTLSConfig(ctx, TLSConfig{
+		MinVersion: "TLS13",
+		Cert:       certFile,
+		Key:        keyFile,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the gRPC client requires ALPN
+	getConfig := cfg.GetConfigForClient
+	cfg.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		c, err := getConfig(hello)
+		if err != nil {
+			return nil, err
+		}
+		c = c.Clone()
+		c.NextProtos = []string{"h2", "http/1.1"}
+		return c, nil
+	}
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{
+		Handler:           http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+		ReadHeaderTimeout: time.Second,
+	}
+	go func() { _ = server.Serve(NewTLSListener(inner, cfg)) }()
+	t.Cleanup(func() { server.Close() })
+	return inner.Addr().String(), caFile
+}
+
+// the server certificate of an IP target is verified by its IP SANs
+func TestClientVerifiesIPTarget(t *testing.T) {
+	addr, caFile := startIPServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg, err := BuildClientTLSConfig(ctx, TLSConfig{
+		MinVersion: "TLS13",
+		CAs:        []string{caFile},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(addr)
+
+	t.Run("http", func(t *testing.T) {
+		client := &http.Client{Transport: &http.Transport{
+			DialTLSContext: NewTLSDialer(cfg),
+		}}
+		resp, err := client.Get("https://" + addr + "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	})
+	tests := []struct {
+		name      string
+		authority string
+		wantErr   bool
+	}{
+		{"grpc", addr, false},
+		{"grpc other host", "localhost:" + port, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer raw.Close()
+			conn, _, err := NewHandshakeCredentials(cfg).
+				ClientHandshake(ctx, tt.authority, raw)
+			if err == nil {
+				conn.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// clientHandshakes returns the recorded client handshakes by outcome and reason
+// and the number of recorded durations
+func clientHandshakes(t *testing.T) (counts map[string]int64, durations uint64) {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := testMetricReader().Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	counts = map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					if usage, _ := dp.Attributes.Value("usage"); usage.AsString() ==
+						CertUsageClient {
+
+						outcome, _ := dp.Attributes.Value("outcome")
+						reason, _ := dp.Attributes.Value("reason")
+						counts[outcome.AsString()+"/"+reason.AsString()] += dp.Value
+					}
+				}
+			case metricdata.Histogram[float64]:
+				for _, dp := range data.DataPoints {
+					if usage, _ := dp.Attributes.Value("usage"); usage.AsString() ==
+						CertUsageClient {
+
+						durations += dp.Count
+					}
+				}
+			}
+		}
+	}
+	return counts, durations
+}
+
+// the client handshakes are recorded with their duration, including failures
+// in crypto/tls before VerifyConnection
+func TestClientRecordsHandshakes(t *testing.T) {
+	addr, caFile := startIPServer(t)
+	_, port, _ := net.SplitHostPort(addr)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	withCA, err := BuildClientTLSConfig(ctx, TLSConfig{
+		MinVersion: "TLS13",
+		CAs:        []string{caFile},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// verified by crypto/tls against the system roots
+	systemRoots, err := BuildClientTLSConfig(ctx, TLSConfig{MinVersion: "TLS13"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	beforeCounts, beforeDurations := clientHandshakes(t)
+
+	for _, target := range []struct {
+		cfg  *tls.Config
+		addr string
+	}{
+		{withCA, addr},
+		{withCA, "localhost:" + port},
+		{systemRoots, addr},
+	} {
+		if conn, err := NewTLSDialer(target.cfg)(ctx, "tcp", target.addr); err == nil {
+			conn.Close()
+		}
+		raw, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn, _, err := NewHandshakeCredentials(target.cfg).
+			ClientHandshake(ctx, target.addr, raw)
+		if err == nil {
+			conn.Close()
+		}
+		raw.Close()
+	}
+
+	counts, durations := clientHandshakes(t)
+	want := map[string]int64{
+		"success/":           2,
+		"failure/bad_san":    2,
+		"failure/unknown_ca": 2,
+	}
+	for k, v := range want {
+		if got := counts[k] - beforeCounts[k]; got != v {
+			t.Errorf("client handshakes %s = %d, want %d", k, got, v)
+		}
+	}
+	if got := durations - beforeDurations; got != 6 {
+		t.Errorf("client handshake durations = %d, want 6", got)
+	}
+}
@@ -0,0 +1,196 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/mpapenbr/otlpdemo/log"
+)
+
+// TLSReloadStatus is the state of a TLSReloader
+type TLSReloadStatus struct {
+	Usage         string    `json:"usage"`
+	Reloads       uint64    `json:"reloads"`  // successful (re)loads
+	Failures      uint64    `json:"failures"` // rejected configs
+	LastReload    time.Time `json:"lastReload,omitzero"`
+	LastError     string    `json:"lastError,omitempty"`
+	LastErrorTime time.Time `json:"lastErrorTime,omitzero"`
+	Fingerprint   string    `json:"fingerprint,omitempty"` // of the current leaf
+	NotAfter      time.Time `json:"notAfter,omitzero"`
+}
+
+// the started reloaders
+var reloaders sync.Map // *TLSReloader -> struct{}
+
+// the metrics are created on first use
+var reloadCounter = sync.OnceValue(func() metric.Int64Counter {
+	meter := otel.Meter("otlpdemo/tls")
+	counter, err := meter.Int64Counter("tls.reloads",
+		metric.WithDescription("Number of TLS config reloads by outcome"),
+		metric.WithUnit("{reload}"))
+	if err != nil {
+		log.Warn("could not create TLS reload counter", log.ErrorField(err))
+	}
+	_, err = meter.Int64ObservableGauge("tls.reload.certificate",
+		metric.WithDescription("Current certificate of a TLS reloader (always 1)"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			for _, s := range TLSReloadStatuses() {
+				o.Observe(1, metric.WithAttributes(
+					attribute.String("usage", s.Usage),
+					attribute.String("fingerprint", s.Fingerprint)))
+			}
+			return nil
+		}))
+	if err != nil {
+		log.Warn("could not create TLS reload gauge", log.ErrorField(err))
+	}
+	return counter
+})
+
+func recordReload(usage string, err error) {
+	counter := reloadCounter()
+	if counter == nil {
+		return
+	}
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+	counter.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String("usage", usage),
+		attribute.String("outcome", outcome)))
+}
+
+// TLSReloadStatuses returns the status of all started reloaders
+func TLSReloadStatuses() []TLSReloadStatus {
+	ret := []TLSReloadStatus{}
+	reloaders.Range(func(key, _ any) bool {
+		//nolint:errcheck // only reloaders are stored
+		ret = append(ret, key.(*TLSReloader).Status())
+		return true
+	})
+	return ret
+}
+
+// NewTLSStatusHandler serves TLSReloadStatuses as JSON array
+func NewTLSStatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(TLSReloadStatuses()); err != nil {
+			log.Error("could not encode TLS status", log.ErrorField(err))
+		}
+	})
+}
+
+// validateTLSConfig checks the leaf certificate of cfg (if any):
+//   - the private key matches the certificate
+//   - now is within the validity period
+//   - the chain verifies against the configured CAs (see verifyChain)
+//
+//nolint:whitespace // editor/linter issue
+func validateTLSConfig(
+	cfg *tls.Config,
+	loaded []LoadedCerts,
+	now time.Time,
+) error {
+	if len(cfg.Certificates) == 0 {
+		return nil
+	}
+	cert := cfg.Certificates[0]
+	leaf := cert.Leaf
+	if leaf == nil {
+		var err error
+		if leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return err
+		}
+	}
+	signer, ok := cert.PrivateKey.(crypto.Signer)
+	if !ok {
+		return errors.New("unsupported private key type")
+	}
+	pub, ok := leaf.PublicKey.(interface{ Equal(x crypto.PublicKey) bool })
+	if !ok || !pub.Equal(signer.Public()) {
+		return errors.New("private key does not match certificate")
+	}
+	if now.Before(leaf.NotBefore) {
+		return fmt.Errorf("certificate not valid before %s",
+			leaf.NotBefore.Format(time.RFC3339))
+	}
+	if now.After(leaf.NotAfter) {
+		return fmt.Errorf("certificate expired at %s",
+			leaf.NotAfter.Format(time.RFC3339))
+	}
+	return verifyChain(cert, leaf, loaded, now)
+}
+
+// verifyChain verifies leaf with the intermediates of cert against the
+// configured CAs containing the issuer of the chain, the CAs are checked
+// before the client CAs. The configured CAs are the ones of the peer, so the
+// chain isn't verified if none of them issued it (e.g. separate server and
+// client CAs).
+//
+//nolint:whitespace // editor/linter issue
+func verifyChain(
+	cert tls.Certificate,
+	leaf *x509.Certificate,
+	loaded []LoadedCerts,
+	now time.Time,
+) error {
+	intermediates := x509.NewCertPool()
+	issuers := [][]byte{leaf.RawIssuer}
+	for _, der := range cert.Certificate[1:] {
+		c, err := x509.ParseCertificate(der)
+		if err != nil {
+			return err
+		}
+		intermediates.AddCert(c)
+		issuers = append(issuers, c.RawIssuer)
+	}
+	isIssuer := func(c *x509.Certificate) bool {
+		return slices.ContainsFunc(issuers, func(raw []byte) bool {
+			return bytes.Equal(raw, c.RawSubject)
+		})
+	}
+	for _, usage := range []string{CertUsageCA, CertUsageClientCA} {
+		roots := x509.NewCertPool()
+		found := false
+		for _, e := range loaded {
+			if e.Usage != usage {
+				continue
+			}
+			for _, c := range e.Certs {
+				roots.AddCert(c)
+				found = found || isIssuer(c)
+			}
+		}
+		if !found {
+			continue
+		}
+		_, err := leaf.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			CurrentTime:   now,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		if err != nil {
+			return fmt.Errorf("chain doesn't verify against the %s certificates: %w",
+				usage, err)
+		}
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCert creates a certificate issued by issuer (nil: a self-signed CA)
+//
+//nolint:whitespace // editor/linter issue
+func newTestCert(
+	t *testing.T,
+	cn string,
+	issuer *testCert,
+	notAfter time.Time,
+	usages ...x509.ExtKeyUsage,
+) *testCert {
+	t.Helper()
+	return createTestCert(t, cn, issuer, issuer == nil, notAfter, usages)
+}
+
+// newTestIntermediate creates an intermediate CA issued by issuer
+//
+//nolint:whitespace // editor/linter issue
+func newTestIntermediate(
+	t *testing.T,
+	cn string,
+	issuer *testCert,
+	notAfter time.Time,
+) *testCert {
+	t.Helper()
+	return createTestCert(t, cn, issuer, true, notAfter, nil)
+}
+
+//nolint:whitespace // editor/linter issue
+func createTestCert(
+	t *testing.T,
+	cn string,
+	issuer *testCert,
+	isCA bool,
+	notAfter time.Time,
+	usages []x509.ExtKeyUsage,
+) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  usages,
+	}
+	if ip := net.ParseIP(cn); ip != nil {
+		tmpl.IPAddresses = []net.IP{ip}
+	} else {
+		tmpl.DNSNames = []string{cn}
+	}
+	if isCA {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	}
+	parent, signer := tmpl, key
+	if issuer != nil {
+		parent, signer = issuer.cert, issuer.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey,
+		signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: c, key: key}
+}
+
+// write stores the certificate and key as <name>.crt and <name>.key in dir
+func (c *testCert) write(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	keyDER, err := x509.MarshalPKCS8PrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	for file, block := range map[string]*pem.Block{
+		certFile: {Type: "CERTIFICATE", Bytes: c.cert.Raw},
+		keyFile:  {Type: "PRIVATE KEY", Bytes: keyDER},
+	} {
+		if err := os.WriteFile(file, pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return certFile, keyFile
+}
+
+// the server and the client certificates are issued by different CAs
+func TestReloaderSplitCAMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	valid := time.Now().Add(24 * time.Hour)
+	serverCA := newTestCert(t, "server-ca", nil, valid)
+	clientCA := newTestCert(t, "client-ca", nil, valid)
+	serverCAFile, _ := serverCA.write(t, dir, "server-ca")
+	clientCAFile, _ := clientCA.write(t, dir, "client-ca")
+	serverCert, serverKey := newTestCert(t, "server", serverCA, valid,
+		x509.ExtKeyUsageServerAuth).write(t, dir, "server")
+	clientCert, clientKey := newTestCert(t, "client", clientCA, valid,
+		x509.ExtKeyUsageClientAuth).write(t, dir, "client")
+
+	if _, err := NewTLSReloader(TLSConfig{
+		MinVersion: "TLS13",
+		Cert:       serverCert,
+		Key:        serverKey,
+		ClientCAs:  []string{clientCAFile},
+		ClientAuth: "require-and-verify",
+	}); err != nil {
+		t.Errorf("server: %v", err)
+	}
+	if _, err := NewClientTLSReloader(TLSConfig{
+		MinVersion: "TLS13",
+		Cert:       clientCert,
+		Key:        clientKey,
+		CAs:        []string{serverCAFile},
+	}); err != nil {
+		t.Errorf("client: %v", err)
+	}
+}
+
+func TestValidateTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	valid := time.Now().Add(24 * time.Hour)
+	ca := newTestCert(t, "ca", nil, valid)
+	caFile, _ := ca.write(t, dir, "ca")
+	// same subject as ca, but another key
+	otherCAFile, _ := newTestCert(t, "ca", nil, valid).write(t, dir, "other-ca")
+	unrelatedCAFile, _ := newTestCert(t, "unrelated", nil, valid).
+		write(t, dir, "unrelated")
+	validCert, validKey := newTestCert(t, "valid", ca,
+		time.Now().Add(time.Hour)).write(t, dir, "valid")
+	expiredCert, expiredKey := newTestCert(t, "expired", ca,
+		time.Now().Add(-time.Minute)).write(t, dir, "expired")
+
+	// leaf issued by an intermediate, the cert file contains both
+	intermediate := newTestIntermediate(t, "intermediate", ca, valid)
+	chainCert, chainKey := newTestCert(t, "chain", intermediate,
+		time.Now().Add(time.Hour)).write(t, dir, "chain")
+	appendPEM(t, chainCert, intermediate.cert.Raw)
+
+	tests := []struct {
+		name      string
+		cert, key string
+		cas       []string
+		clientCAs []string
+		wantErr   bool
+	}{
+		{"valid", validCert, validKey, nil, nil, false},
+		{"expired", expiredCert, expiredKey, nil, nil, true},
+		{"key mismatch", validCert, expiredKey, nil, nil, true},
+		{"issued by ca", validCert, validKey, []string{caFile}, nil, false},
+		{"issued by client ca", validCert, validKey, nil, []string{caFile}, false},
+		{"intermediate in cert file", chainCert, chainKey, []string{caFile}, nil, false},
+		{"issuer not configured", validCert, validKey, []string{unrelatedCAFile},
+			[]string{unrelatedCAFile}, false},
+		{"issuer with other key", validCert, validKey, []string{otherCAFile}, nil, true},
+		{"issuer with other key in client cas", validCert, validKey,
+			[]string{unrelatedCAFile}, []string{otherCAFile}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, loaded, err := LoadTLSConfig(TLSConfig{
+				MinVersion: "TLS13",
+				Cert:       tt.cert,
+				Key:        tt.key,
+				CAs:        tt.cas,
+				ClientCAs:  tt.clientCAs,
+			}, CertUsageServer)
+			if err == nil {
+				err = validateTLSConfig(cfg, loaded, time.Now())
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// appendPEM appends the DER encoded certificate to file
+func appendPEM(t *testing.T, file string, der []byte) {
+	t.Helper()
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+		t.Fatal(err)
+	}
+}
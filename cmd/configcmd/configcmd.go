@@ -0,0 +1,212 @@
+package configcmd
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+
+	"github.com/mpapenbr/otlpdemo/cmd/config"
+	"github.com/mpapenbr/otlpdemo/log"
+)
+
+// values of flags with matching names are not shown. Whole name segments
+// are matched, tls-key is the path of the key, not the key itself.
+var secretFlag = regexp.MustCompile(
+	`(?i)(^|-)(password|secrets?|tokens?|api-key)($|-)`)
+
+const redacted = "*****"
+
+type (
+	entry struct {
+		Key    string `json:"key"    yaml:"key"` // key in config file
+		Value  any    `json:"value"  yaml:"value"`
+		Source string `json:"source" yaml:"source"`
+	}
+	showOutput struct {
+		Flags map[string]entry `json:"flags" yaml:"flags"`
+		Log   map[string]any   `json:"log"   yaml:"log"` // resolved log config
+	}
+	// logView is the part of log.Config which can be marshaled,
+	// the encoders of the zap config are functions.
+	logView struct {
+		DefaultLevel   string                      `yaml:"defaultLevel"`
+		OtelLevel      string                      `yaml:"otelLevel"`
+		Loggers        map[string]log.LoggerConfig `yaml:"loggers"`
+		Zap            zapView                     `yaml:"zap"`
+		Filters        []string                    `yaml:"filters"`
+		Sampling       *log.SamplingConfig         `yaml:"sampling"`
+		RateLimit      *log.RateLimitConfig        `yaml:"rateLimit"`
+		DropReport     log.DropReportConfig        `yaml:"dropReport"`
+		SpanEventLevel string                      `yaml:"spanEventLevel"`
+		EntryMetric    bool                        `yaml:"entryMetric"`
+		RingBuffer     *log.RingBufferConfig       `yaml:"ringBuffer"`
+	}
+	zapView struct {
+		Development       bool     `yaml:"development"`
+		DisableCaller     bool     `yaml:"disableCaller"`
+		DisableStacktrace bool     `yaml:"disableStacktrace"`
+		Encoding          string   `yaml:"encoding"`
+		OutputPaths       []string `yaml:"outputPaths"`
+		ErrorOutputPaths  []string `yaml:"errorOutputPaths"`
+	}
+)
+
+var output string
+
+func NewConfigCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "config",
+		Short: "commands to inspect the configuration",
+		Long:  ``,
+		// the config is shown even if it is invalid
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	}
+	cmd.AddCommand(newShowCommand())
+	return &cmd
+}
+
+func newShowCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "show [command path...]",
+		Short: "prints the effective config of a command",
+		Long: `Prints the effective value of each flag of the given command
+(e.g. 'config show web grpcserver') together with its source:
+default, file <config file>, env <env var> or flag. The key is used in the
+config file, e.g. web.grpcserver.addr.
+Values of flags named like password, secret, token or key are redacted.
+The section log shows the resolved log config of the command.
+Without a command path the root flags are shown.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return showConfig(cmd, args)
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "yaml", "output format (yaml, json)")
+	return &cmd
+}
+
+func showConfig(cmd *cobra.Command, path []string) error {
+	target, rest, err := cmd.Root().Find(path)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("unknown command %q", strings.Join(path, " "))
+	}
+	logConfig, err := resolveLogConfig(target)
+	if err != nil {
+		return err
+	}
+	out := showOutput{Flags: collectEntries(target), Log: logConfig}
+	switch strings.ToLower(output) {
+	case "yaml":
+		return writeYAML(os.Stdout, out)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	default:
+		return fmt.Errorf("unknown output format %q", output)
+	}
+}
+
+// collectEntries returns the local and inherited flags of cmd by name
+func collectEntries(cmd *cobra.Command) map[string]entry {
+	ret := map[string]entry{}
+	add := func(f *pflag.Flag) {
+		if f.Name == "help" {
+			return
+		}
+		e := entry{
+			Key:    config.FlagKey(cmd, f.Name),
+			Value:  flagValue(f),
+			Source: config.GetFlagSource(f).String(),
+		}
+		if secretFlag.MatchString(f.Name) && f.Value.String() != "" {
+			e.Value = redacted
+		}
+		ret[f.Name] = e
+	}
+	cmd.InheritedFlags().VisitAll(add)
+	cmd.LocalFlags().VisitAll(add)
+	return ret
+}
+
+// flagValue returns the value of f typed for the output
+func flagValue(f *pflag.Flag) any {
+	if s, ok := f.Value.(pflag.SliceValue); ok {
+		return s.GetSlice()
+	}
+	switch f.Value.Type() {
+	case "bool":
+		if b, err := strconv.ParseBool(f.Value.String()); err == nil {
+			return b
+		}
+	case "int", "int32", "int64":
+		if i, err := strconv.ParseInt(f.Value.String(), 10, 64); err == nil {
+			return i
+		}
+	}
+	return f.Value.String()
+}
+
+// resolveLogConfig returns the log config used by cmd (see
+// config.BuildLogConfig) as map, so the keys are the same for yaml and json.
+func resolveLogConfig(cmd *cobra.Command) (map[string]any, error) {
+	appConfig, err := config.NewAppConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := config.BuildLogConfig(appConfig.Log)
+	if err != nil {
+		return nil, err
+	}
+	// the levels given by flag override the log config (see log.WithLogLevel)
+	view := logView{
+		DefaultLevel:   cmp.Or(appConfig.Log.Level, cfg.DefaultLevel),
+		OtelLevel:      cmp.Or(appConfig.Log.OtelLevel, cfg.OtelLevel),
+		Loggers:        cfg.Loggers,
+		Filters:        cfg.Filters,
+		Sampling:       cfg.Sampling,
+		RateLimit:      cfg.RateLimit,
+		DropReport:     cfg.DropReport,
+		SpanEventLevel: cfg.SpanEventLevel,
+		EntryMetric:    cfg.EntryMetric,
+		RingBuffer:     cfg.RingBuffer,
+		Zap: zapView{
+			Development:       cfg.Zap.Development,
+			DisableCaller:     cfg.Zap.DisableCaller,
+			DisableStacktrace: cfg.Zap.DisableStacktrace,
+			Encoding:          cfg.Zap.Encoding,
+			OutputPaths:       cfg.Zap.OutputPaths,
+			ErrorOutputPaths:  cfg.Zap.ErrorOutputPaths,
+		},
+	}
+	data, err := yaml.Marshal(view)
+	if err != nil {
+		return nil, err
+	}
+	ret := map[string]any{}
+	if err := yaml.Unmarshal(data, &ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func writeYAML(w io.Writer, out showOutput) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,80 @@
+package configcmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestCollectEntriesRedactsSecrets(t *testing.T) {
+	cmd := &cobra.Command{Use: "test"}
+	for _, name := range []string{
+		"password", "db-password", "secrets-file", "api-token", "api-key",
+		"tls-key", "keystore", "addr", "log-level", "empty-secret",
+	} {
+		cmd.Flags().String(name, "", "")
+		if name != "empty-secret" {
+			if err := cmd.Flags().Set(name, "value"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	entries := collectEntries(cmd)
+	tests := []struct {
+		name string
+		want any
+	}{
+		{"password", redacted},
+		{"db-password", redacted},
+		{"secrets-file", redacted},
+		{"api-token", redacted},
+		{"api-key", redacted},
+		{"tls-key", "value"}, // a path
+		{"keystore", "value"},
+		{"addr", "value"},
+		{"log-level", "value"},
+		{"empty-secret", ""}, // nothing to hide
+	}
+	for _, tt := range tests {
+		if got := entries[tt.name].Value; got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestResolveLogConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "logger.yml")
+	data := "defaultLevel: warn\nloggers:\n  db: error\n"
+	if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cmd := &cobra.Command{Use: "test"}
+	cmd.PersistentFlags().String("log-config", file, "")
+	cmd.PersistentFlags().String("log-level", "", "")
+	cmd.PersistentFlags().String("otel-log-level", "", "")
+
+	got, err := resolveLogConfig(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["defaultLevel"] != "warn" {
+		t.Errorf("defaultLevel = %v, want warn", got["defaultLevel"])
+	}
+	loggers, _ := got["loggers"].(map[string]any)
+	if db, _ := loggers["db"].(map[string]any); db["level"] != "error" {
+		t.Errorf("loggers = %v, want db with level error", got["loggers"])
+	}
+
+	// the flag overrides the log config
+	if err := cmd.PersistentFlags().Set("log-level", "debug"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = resolveLogConfig(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if got["defaultLevel"] != "debug" {
+		t.Errorf("defaultLevel = %v, want debug", got["defaultLevel"])
+	}
+}
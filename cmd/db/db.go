@@ -30,20 +30,20 @@ func NewDBCommand() *cobra.Command {
 		Short: "tests database connectivity",
 		Long:  ``,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			doDBStuff()
+			doDBStuff(config.GetFromContext(cmd.Context()).DB)
 			return nil
 		},
 	}
-	cmd.Flags().StringVar(&config.DBConf.Host, "host", "localhost", "database host")
-	cmd.Flags().IntVar(&config.DBConf.Port, "port", 5432, "database port")
-	cmd.Flags().StringVar(&config.DBConf.Database, "database", "postgres", "database name")
-	cmd.Flags().StringVar(&config.DBConf.StaticSecrets.User, "user", "", "database user")
-	cmd.Flags().StringVar(&config.DBConf.StaticSecrets.Password, "password", "", "database password")
-	cmd.Flags().StringVar(&config.DBConf.SecretsFile, "secrets-file", "", "path to secrets file")
-	cmd.Flags().StringVar(&config.DBConf.SSLMode, "sslmode", "disable", "database SSL mode")
-	cmd.Flags().StringVar(&config.DBConf.TLSCert, "tls-cert", "", "path to TLS certificate")
-	cmd.Flags().StringVar(&config.DBConf.TLSKey, "tls-key", "", "path to TLS key")
-	cmd.Flags().StringVar(&config.DBConf.TLSCA, "tls-ca", "", "path to TLS CA")
+	cmd.Flags().String("host", "localhost", "database host")
+	cmd.Flags().Int("port", 5432, "database port")
+	cmd.Flags().String("database", "postgres", "database name")
+	cmd.Flags().String("user", "", "database user")
+	cmd.Flags().String("password", "", "database password")
+	cmd.Flags().String("secrets-file", "", "path to secrets file")
+	cmd.Flags().String("sslmode", "disable", "database SSL mode")
+	cmd.Flags().String("tls-cert", "", "path to TLS certificate")
+	cmd.Flags().String("tls-key", "", "path to TLS key")
+	cmd.Flags().String("tls-ca", "", "path to TLS CA")
 	cmd.Flags().DurationVar(&poolMaxLife, "pool-max-life", 5*time.Minute, "maximum lifetime of a connection in the pool")
 	cmd.Flags().DurationVar(&tickerDuration, "ticker", 5*time.Second, "duration for ticker interval")
 	cmd.Flags().DurationVar(&longRunningTickerDuration, "long-running-ticker", 30*time.Second, "duration for long running ticker interval")
@@ -56,9 +56,9 @@ var tickerDuration,
 	longRunningDuration,
 	poolMaxLife time.Duration
 
-func doDBStuff() error {
-	log.Debug("Connecting to database", log.Any("conf", config.DBConf))
-	dbDemo, err := newDemoDB(config.DBConf)
+func doDBStuff(conf config.DBConfig) error {
+	log.Debug("Connecting to database", log.Any("conf", conf))
+	dbDemo, err := newDemoDB(conf)
 	if err != nil {
 		log.Error("could not create DB connection", log.ErrorField(err))
 		return err
@@ -80,7 +80,7 @@ type (
 func newDemoDB(conf config.DBConfig) (*demoDB, error) {
 	pool, err := createPool(conf)
 	if err != nil {
-		return nil, fmt.Errorf("could not create DB pool: %w", err)
+		return nil, log.NewErrorf("could not create DB pool: %w", err)
 	}
 	return &demoDB{
 		pool:   pool,
@@ -144,6 +144,7 @@ func createPool(conf config.DBConfig) (*pgxpool.Pool, error) {
 			if ok := caCertPool.AppendCertsFromPEM(caCert); !ok {
 				return nil, fmt.Errorf("failed to append server certificate")
 			}
+			config.Certs.RegisterPEM(conf.TLSCA, config.CertUsageCA, caCert)
 		}
 		poolCfg.ConnConfig.TLSConfig = &tls.Config{
 			MinVersion: tls.VersionTLS13,
@@ -155,6 +156,7 @@ func createPool(conf config.DBConfig) (*pgxpool.Pool, error) {
 				if certErr != nil {
 					return nil, certErr
 				}
+				config.Certs.Register(conf.TLSCert, config.CertUsageClient, cert.Leaf)
 				log.Debug("Providing client certificate",
 					log.String("cn", cert.Leaf.Subject.CommonName),
 					log.String("serial", cert.Leaf.SerialNumber.String()),
@@ -168,6 +170,7 @@ func createPool(conf config.DBConfig) (*pgxpool.Pool, error) {
 			ServerName: conf.Host,
 		}
 	}
+	poolCfg.ConnConfig.Tracer = log.NewPgxTracer(log.Default())
 	poolCfg.MaxConns = 10
 	poolCfg.MinConns = 2
 	if poolMaxLife > 0 {
@@ -0,0 +1,75 @@
+package logcmd
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mpapenbr/otlpdemo/cmd/config"
+	"github.com/mpapenbr/otlpdemo/log"
+)
+
+func NewLogCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "log",
+		Short: "commands to check the logger configuration",
+		Long:  ``,
+		// the logger setup of the root command would fail on an invalid config
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	}
+	cmd.AddCommand(newCheckCommand())
+	return &cmd
+}
+
+func newCheckCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "check [logger names...]",
+		Short: "validates the log config and prints the effective levels",
+		Long: `Validates the log config (see --log-config) and prints the effective
+console and OTLP level for each logger name. If no names are given, the keys of
+the loggers section are used.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return checkConfig(cmd, args)
+		},
+	}
+	return &cmd
+}
+
+func checkConfig(cmd *cobra.Command, names []string) error {
+	appConfig, err := config.NewAppConfig(cmd)
+	if err != nil {
+		return err
+	}
+	if err = appConfig.Validate(); err != nil {
+		return err
+	}
+	logConfig, err := config.BuildLogConfig(appConfig.Log)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		names = slices.Sorted(maps.Keys(logConfig.Loggers))
+	}
+	// only the resolution of the levels is needed, nothing is logged
+	l := log.New(
+		log.WithLogConfig(logConfig),
+		log.WithLogLevel(appConfig.Log.Level),
+		log.WithOtelLogLevel(appConfig.Log.OtelLevel),
+	)
+	//nolint:errcheck // by design
+	defer l.Close()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LOGGER\tLEVEL\tOTEL LEVEL")
+	fmt.Fprintf(w, "%s\t%s\t%s\n", "(root)", l.Level(), l.OtelLevel())
+	for _, name := range names {
+		child := l.Named(name)
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, child.Level(), child.OtelLevel())
+	}
+	return w.Flush()
+}
@@ -20,16 +20,16 @@ func NewLogEmitCommand() *cobra.Command {
 		Short: "simple test via direct emit with record",
 		Long:  ``,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return doLogEmit()
+			return doLogEmit(config.GetFromContext(cmd.Context()))
 		},
 	}
 	return &cmd
 }
 
-func doLogEmit() error {
+func doLogEmit(cfg *config.AppConfig) error {
 	ctx := context.Background()
 	t, err := otel.SetupTelemetry(
-		otel.WithTelemetryOutput(otel.ParseTelemetryOutput(config.OtelOutput)),
+		otel.WithTelemetryOutput(otel.ParseTelemetryOutput(cfg.Telemetry.Output)),
 		otel.WithTelemetryContext(ctx),
 	)
 	if err != nil {
@@ -1,6 +1,7 @@
 package otlplog
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 
@@ -24,25 +25,33 @@ Uses a zap logger (created and configured here) in combination with otelzap.
 Note: If you see messages in loki that shouldn't be there, check the setup in rootCmd
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return doOtelZapLog()
+			return doOtelZapLog(config.GetFromContext(cmd.Context()))
 		},
 	}
 	return &cmd
 }
 
 //nolint:funlen // ok here
-func doOtelZapLog() error {
+func doOtelZapLog(appCfg *config.AppConfig) error {
 	ctx := context.Background()
 	t, err := otel.SetupTelemetry(
-		otel.WithTelemetryOutput(otel.ParseTelemetryOutput(config.OtelOutput)),
+		otel.WithTelemetryOutput(otel.ParseTelemetryOutput(appCfg.Telemetry.Output)),
 		otel.WithTelemetryContext(ctx),
 	)
 	if err != nil {
 		return fmt.Errorf("could not setup telemetry: %w", err)
 	}
 
+	logConfig, err := config.BuildLogConfig(appCfg.Log)
+	if err != nil {
+		return fmt.Errorf("could not load log config: %w", err)
+	}
 	cfg := zap.NewProductionConfig()
-	cfg.Level, _ = zap.ParseAtomicLevel(config.LogLevel)
+	cfg.Level, err = zap.ParseAtomicLevel(
+		cmp.Or(appCfg.Log.Level, logConfig.DefaultLevel))
+	if err != nil {
+		return err
+	}
 	logger, _ := cfg.Build()
 	otelSeverity := &minsevSeverity{convertLevel(cfg.Level.Level())}
 	//nolint:errcheck // no error check for example
@@ -2,6 +2,7 @@ package otlplog
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -18,31 +19,44 @@ func NewZapContextCommand() *cobra.Command {
 		Short: "simple test via context base zap",
 		Long:  ``,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return doZapContextLog()
+			return doZapContextLog(config.GetFromContext(cmd.Context()))
 		},
 	}
 	return &cmd
 }
 
-func doZapContextLog() error {
+func doZapContextLog(cfg *config.AppConfig) error {
 	ctx := context.Background()
 	t, err := otel.SetupTelemetry(
-		otel.WithTelemetryOutput(otel.ParseTelemetryOutput(config.OtelOutput)),
+		otel.WithTelemetryOutput(otel.ParseTelemetryOutput(cfg.Telemetry.Output)),
 		otel.WithTelemetryContext(ctx),
 	)
 	if err != nil {
 		return fmt.Errorf("could not setup telemetry: %w", err)
 	}
-	logger, _ := log.NewZapWithContextBasedOTLP(global.GetLoggerProvider())
+	logConfig, err := config.BuildLogConfig(cfg.Log)
+	if err != nil {
+		return fmt.Errorf("could not load log config: %w", err)
+	}
+	logger, err := log.NewZapWithContextBasedOTLP(global.GetLoggerProvider(),
+		log.WithLogConfig(logConfig),
+		log.WithLogLevel(cfg.Log.Level))
+	if err != nil {
+		return fmt.Errorf("could not create logger: %w", err)
+	}
 
 	logger.Info("standard zapcontext message without context")
 
 	spanCtx, span := tracer.Start(ctx, "testspan zapcontext")
 	defer span.End()
 
-	// you wouldn't see the attributes in OTLP since they are not handled
+	// the fields are converted to OTLP attributes
 	logger.InfoContext(spanCtx, "zapcontext message in span with context",
 		log.String("someLogAttr", "someValue"))
+	logger.WarnContext(spanCtx, "zapcontext warn message in span with context",
+		log.String("someLogAttr", "someValue"),
+		log.Any("nested", map[string]int{"a": 1}),
+		log.ErrorField(errors.New("some error")))
 	span.End()
 	t.Shutdown()
 	return nil
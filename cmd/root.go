@@ -8,14 +8,17 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 
+	"github.com/mpapenbr/otlpdemo/cmd/certs"
 	"github.com/mpapenbr/otlpdemo/cmd/config"
+	"github.com/mpapenbr/otlpdemo/cmd/configcmd"
 	"github.com/mpapenbr/otlpdemo/cmd/db"
+	"github.com/mpapenbr/otlpdemo/cmd/logcmd"
 	"github.com/mpapenbr/otlpdemo/cmd/raw"
 	"github.com/mpapenbr/otlpdemo/cmd/sample"
 	"github.com/mpapenbr/otlpdemo/cmd/web"
@@ -45,19 +48,24 @@ var rootCmd = &cobra.Command{
 	Long:    ``,
 	Version: version.FullVersion,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		logConfig := log.DefaultDevConfig()
-		if config.LogConfig != "" {
-			var err error
-			logConfig, err = log.LoadConfig(config.LogConfig)
-			if err != nil {
-				log.Fatal("could not load log config", log.ErrorField(err))
-			}
+		appConfig, err := config.NewAppConfig(cmd)
+		if err == nil {
+			err = appConfig.Validate()
+		}
+		if err != nil {
+			log.Fatal("invalid config", log.ErrorField(err))
+		}
+		logConfig, err := config.BuildLogConfig(appConfig.Log)
+		if err != nil {
+			log.Fatal("could not load log config", log.ErrorField(err))
 		}
 
-		if config.EnableTelemetry {
-			var err error
+		config.Certs.SetWarnThresholds(appConfig.TLS.WarnThresholds)
+		if appConfig.Telemetry.Enabled {
 			if telemetry, err = otel.SetupTelemetry(
-				otel.WithTelemetryOutput(otel.ParseTelemetryOutput(config.OtelOutput)),
+				otel.WithTelemetryOutput(
+					otel.ParseTelemetryOutput(appConfig.Telemetry.Output)),
+				otel.WithCertObserver(config.Certs),
 			); err != nil {
 				log.Error("Could not setup telemetry", log.ErrorField(err))
 			}
@@ -65,13 +73,16 @@ var rootCmd = &cobra.Command{
 
 		l := log.New(
 			log.WithLogConfig(logConfig),
-			log.WithLogLevel(config.LogLevel),
+			log.WithLogLevel(appConfig.Log.Level),
+			log.WithOtelLogLevel(appConfig.Log.OtelLevel),
 			log.WithTelemetry(telemetry),
 			log.WithRemoveContextFields(removeContextFields),
 			log.WithUseZap(useZap),
 		)
-		cmd.SetContext(log.AddToContext(context.Background(), l))
+		cmd.SetContext(config.AddToContext(
+			log.AddToContext(context.Background(), l), appConfig))
 		log.ResetDefault(l)
+		log.SetSlogDefault(l)
 	},
 
 	// Uncomment the following line if your bare application
@@ -86,11 +97,14 @@ func Execute() {
 		os.Exit(1)
 	}
 
+	if err := config.Certs.Close(); err != nil {
+		log.Warn("could not close cert inventory", log.ErrorField(err))
+	}
+	//nolint:errcheck // by design
+	log.Default().Close()
 	if telemetry != nil {
 		telemetry.Shutdown()
 	}
-	//nolint:errcheck // by design
-	log.Sync()
 }
 
 func init() {
@@ -103,25 +117,29 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "",
 		"config file (default is $HOME/.otlpdemo.yml)")
 
-	rootCmd.PersistentFlags().BoolVar(&config.EnableTelemetry,
-		"enable-telemetry",
+	rootCmd.PersistentFlags().Bool("enable-telemetry",
 		false,
 		"enables telemetry")
 
-	rootCmd.PersistentFlags().StringVar(&config.OtelOutput, "otel-output", "stdout",
+	rootCmd.PersistentFlags().String("otel-output", "stdout",
 		"output destination (stdout, grpc)")
-	rootCmd.PersistentFlags().StringVar(&config.TelemetryEndpoint,
-		"telemetry-endpoint",
+	rootCmd.PersistentFlags().String("telemetry-endpoint",
 		"localhost:4317",
 		"Endpoint that receives open telemetry data")
-	rootCmd.PersistentFlags().StringVar(&config.LogLevel,
-		"log-level",
-		"info",
-		"controls the log level (debug, info, warn, error, fatal)")
-	rootCmd.PersistentFlags().StringVar(&config.LogConfig,
-		"log-config",
+	rootCmd.PersistentFlags().String("log-level",
 		"",
-		"configures the logger")
+		"controls the log level (debug, info, warn, error, fatal). "+
+			"If not set, the defaultLevel of the log config is used")
+	rootCmd.PersistentFlags().String("otel-log-level",
+		"",
+		"controls the log level for OTLP output (default: same as log-level)")
+	rootCmd.PersistentFlags().String("log-config",
+		"",
+		"configures the logger. Overrides the section 'log' of the config file, "+
+			"values may be overridden by env vars OTLPDEMO_LOG_*")
+	rootCmd.PersistentFlags().DurationSlice("tls-cert-warn",
+		[]time.Duration{30 * 24 * time.Hour, 7 * 24 * time.Hour, 24 * time.Hour},
+		"log a warning when a loaded certificate expires within these durations")
 	rootCmd.PersistentFlags().BoolVar(&useZap, "use-zap",
 		true,
 		"if true, use output from configured zap logger")
@@ -137,6 +155,12 @@ func init() {
 	rootCmd.AddCommand(raw.NewRawOTLPCommand())
 
 	rootCmd.AddCommand(db.NewDBCommand())
+
+	rootCmd.AddCommand(logcmd.NewLogCommand())
+
+	rootCmd.AddCommand(configcmd.NewConfigCommand())
+
+	rootCmd.AddCommand(certs.NewCertsCommand())
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -168,9 +192,7 @@ func initConfig() {
 	// even those N levels deep
 	cmds := []*cobra.Command{}
 	collectCommands(rootCmd, &cmds)
-
-	bindFlags(rootCmd, viper.GetViper())
-	for _, cmd := range rootCmd.Commands() {
+	for _, cmd := range cmds {
 		bindFlags(cmd, viper.GetViper())
 	}
 }
@@ -182,26 +204,69 @@ func collectCommands(cmd *cobra.Command, commands *[]*cobra.Command) {
 	}
 }
 
-// Bind each cobra flag to its associated viper configuration
-// (config file and environment variable)
+// flat keys already reported as deprecated by bindFlags
+var deprecatedKeys = map[string]struct{}{}
+
+// Bind each cobra flag defined by cmd to its associated viper configuration
+// (config file and environment variable). The keys are hierarchical, e.g.
+// web.grpcserver.addr and OTLPDEMO_WEB_GRPCSERVER_ADDR (see config.FlagKey).
+// Inherited persistent flags are bound by the command which defines them.
+// The flat keys used before (e.g. tls-cert and OTLPDEMO_TLS_CERT) are still
+// read if the hierarchical key isn't set.
 func bindFlags(cmd *cobra.Command, v *viper.Viper) {
-	cmd.Flags().VisitAll(func(f *pflag.Flag) {
-		// Environment variables can't have dashes in them, so bind them to their
-		// equivalent keys with underscores, e.g. --favorite-color to STING_FAVORITE_COLOR
-		if strings.Contains(f.Name, "-") {
-			envVarSuffix := strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
-			if err := v.BindEnv(f.Name,
-				fmt.Sprintf("%s_%s", envPrefix, envVarSuffix)); err != nil {
-				fmt.Fprintf(os.Stderr, "Could not bind env var %s: %v", f.Name, err)
+	bindEnv := func(key, envVar string) {
+		if err := v.BindEnv(key, envVar); err != nil {
+			fmt.Fprintf(os.Stderr, "Could not bind env var %s: %v\n", envVar, err)
+		}
+	}
+	bind := func(flags *pflag.FlagSet) func(f *pflag.Flag) {
+		return func(f *pflag.Flag) {
+			key := config.FlagKey(cmd, f.Name)
+			envVar := config.FlagEnvVar(key)
+			bindEnv(key, envVar)
+			if legacyEnvVar := config.FlagEnvVar(f.Name); key != f.Name {
+				bindEnv(f.Name, legacyEnvVar)
+				if !v.IsSet(key) && v.IsSet(f.Name) {
+					if _, ok := deprecatedKeys[f.Name]; !ok {
+						deprecatedKeys[f.Name] = struct{}{}
+						fmt.Fprintf(os.Stderr,
+							"Config key %s is deprecated, use the key of the command, e.g. %s or %s\n",
+							f.Name, key, envVar)
+					}
+					key, envVar = f.Name, legacyEnvVar
+				}
+			}
+			// Apply the viper config value to the flag when the flag is not set and
+			// viper has a value
+			if f.Changed || !v.IsSet(key) {
+				return
+			}
+			if err := setFlag(flags, f, v.Get(key)); err != nil {
+				fmt.Fprintf(os.Stderr, "Could not set flag value for %s: %v\n", key, err)
+				return
 			}
+			config.SetFlagSource(f, envVar, v.ConfigFileUsed())
 		}
-		// Apply the viper config value to the flag when the flag is not set and viper
-		// has a value
-		if !f.Changed && v.IsSet(f.Name) {
-			val := v.Get(f.Name)
-			if err := cmd.Flags().Set(f.Name, fmt.Sprintf("%v", val)); err != nil {
-				fmt.Fprintf(os.Stderr, "Could set flag value for %s: %v", f.Name, err)
+	}
+	cmd.LocalNonPersistentFlags().VisitAll(bind(cmd.Flags()))
+	cmd.PersistentFlags().VisitAll(bind(cmd.PersistentFlags()))
+}
+
+// setFlag sets the value of f. Lists (from the config file) replace the
+// values of slice flags, other values are parsed like command line values.
+func setFlag(flags *pflag.FlagSet, f *pflag.Flag, val any) error {
+	if list, ok := val.([]any); ok {
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			items := make([]string, len(list))
+			for i, item := range list {
+				items[i] = fmt.Sprint(item)
 			}
+			if err := sv.Replace(items); err != nil {
+				return err
+			}
+			f.Changed = true
+			return nil
 		}
-	})
+	}
+	return flags.Set(f.Name, fmt.Sprintf("%v", val))
 }
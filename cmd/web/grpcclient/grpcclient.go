@@ -23,11 +23,10 @@ func NewSimpleGRPCClientCommand() *cobra.Command {
 		Short: "create a simple gRPC client",
 		Long:  ``,
 		Run: func(cmd *cobra.Command, args []string) {
-			simpleGRPCClient()
+			simpleGRPCClient(config.GetFromContext(cmd.Context()))
 		},
 	}
-	cmd.Flags().StringVar(&config.Address,
-		"addr",
+	cmd.Flags().String("addr",
 		"localhost:8080",
 		"connect to this server address")
 
@@ -35,9 +34,12 @@ func NewSimpleGRPCClientCommand() *cobra.Command {
 }
 
 //nolint:funlen // ok by design
-func simpleGRPCClient() {
-	fmt.Printf("Starting gRPC connection to %s\n", config.Address)
-	myTLS, err := config.BuildClientTLSConfig()
+func simpleGRPCClient(cfg *config.AppConfig) {
+	fmt.Printf("Starting gRPC connection to %s\n", cfg.Server.Address)
+	log.SetGRPCLogger(log.Default(), cfg.Server.GRPCLogVerbosity)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel() // stops watching the cert files
+	myTLS, err := config.BuildClientTLSConfig(ctx, cfg.TLS)
 	if err != nil {
 		log.Error("TLS config error", log.ErrorField(err))
 		return
@@ -46,10 +48,10 @@ func simpleGRPCClient() {
 	if myTLS == nil {
 		creds = insecure.NewCredentials()
 	} else {
-		creds = credentials.NewTLS(myTLS)
+		creds = config.NewHandshakeCredentials(myTLS)
 	}
 
-	conn, err := grpc.NewClient(config.Address,
+	conn, err := grpc.NewClient(cfg.Server.Address,
 		grpc.WithTransportCredentials(creds),
 		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
 	)
@@ -59,7 +61,7 @@ func simpleGRPCClient() {
 	}
 	defer conn.Close()
 
-	log.Debug("gRPC connection established", log.String("address", config.Address))
+	log.Debug("gRPC connection established", log.String("address", cfg.Server.Address))
 	client := pb.NewPetStoreServiceClient(conn)
 	req := &petv1.GetPetRequest{
 		PetId: "1234",
@@ -68,7 +70,7 @@ func simpleGRPCClient() {
 	sendMD := metadata.Pairs(
 		"client-id", "otlpdemo-client",
 	)
-	ctx := metadata.NewOutgoingContext(context.Background(), sendMD)
+	ctx = metadata.NewOutgoingContext(ctx, sendMD)
 	var recvMDHeader, recvMDTrailer metadata.MD
 	resp, err := client.GetPet(ctx, req,
 		grpc.Header(&recvMDHeader),
@@ -19,6 +19,7 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 
+	"github.com/mpapenbr/otlpdemo/authz"
 	"github.com/mpapenbr/otlpdemo/cmd/config"
 	"github.com/mpapenbr/otlpdemo/log"
 )
@@ -31,34 +32,54 @@ func NewSimpleGRPCServerCommand() *cobra.Command {
 		Short: "create a simple gRPC server",
 		Long:  ``,
 		Run: func(cmd *cobra.Command, args []string) {
-			simpleGRPCserver()
+			simpleGRPCserver(config.GetFromContext(cmd.Context()))
 		},
 	}
-	cmd.Flags().StringVar(&config.Address, "addr", ":8080", "listen address")
+	cmd.Flags().String("addr", ":8080", "listen address")
+	cmd.Flags().String("authz-policy", "",
+		"policy file to authorize clients by certificate (see README-tls.md)")
 
 	return &cmd
 }
 
-func simpleGRPCserver() {
-	fmt.Printf("Starting server on %s\n", config.Address)
-	creds, err := config.BuildTransportCredentials()
+func simpleGRPCserver(cfg *config.AppConfig) {
+	fmt.Printf("Starting server on %s\n", cfg.Server.Address)
+	log.SetGRPCLogger(log.Default(), cfg.Server.GRPCLogVerbosity)
+	creds, err := config.BuildTransportCredentials(context.Background(), cfg.TLS)
 	if err != nil {
 		log.Error("TLS config error", log.ErrorField(err))
 		return
 	}
+	var policy *authz.Policy
+	if cfg.Server.AuthzPolicy != "" {
+		if policy, err = authz.LoadPolicy(cfg.Server.AuthzPolicy); err != nil {
+			log.Error("authz policy error", log.ErrorField(err))
+			return
+		}
+	}
 
 	var l net.ListenConfig
-	lis, err := l.Listen(context.Background(), "tcp", config.Address)
+	lis, err := l.Listen(context.Background(), "tcp", cfg.Server.Address)
 	if err != nil {
 		log.Error("error starting listener", log.ErrorField(err))
 		return
 	}
 	statsHandler := grpc.StatsHandler(otelgrpc.NewServerHandler())
 
+	unary := []grpc.UnaryServerInterceptor{
+		TraceIDHeaderInterceptor(),
+		log.UnaryServerInterceptor(log.Default()),
+	}
+	stream := []grpc.StreamServerInterceptor{
+		log.StreamServerInterceptor(log.Default()),
+	}
+	if policy != nil {
+		unary = append(unary, authz.UnaryServerInterceptor(policy))
+		stream = append(stream, authz.StreamServerInterceptor(policy))
+	}
 	srv := grpc.NewServer(grpc.Creds(creds), statsHandler,
-		grpc.ChainUnaryInterceptor(
-			TraceIDHeaderInterceptor(),
-		))
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...))
 	pb.RegisterPetStoreServiceServer(srv, &petServer{})
 	if err := srv.Serve(lis); err != nil {
 		log.Error("error starting server", log.ErrorField(err))
@@ -96,11 +117,12 @@ var _ pb.PetStoreServiceServer = (*petServer)(nil)
 func (s *petServer) GetPet(ctx context.Context, req *petv1.GetPetRequest) (
 	*petv1.GetPetResponse, error,
 ) {
-	log.Debug("GetPet called", log.String("petId", req.PetId), log.Any("spanCtx", ctx))
+	logger := log.GetFromContext(ctx).With(log.String("petId", req.PetId))
+	logger.Debug("GetPet called")
 	span := trace.SpanFromContext(ctx)
 	s.ringTheBell(ctx)
 	if pet, err := s.lookingForRequestedPet(ctx, req.PetId); err != nil {
-		log.Error("pet not found", log.String("petId", req.PetId), log.ErrorField(err))
+		logger.Error("pet not found", log.ErrorField(err))
 		span.SetStatus(codes.Error, "pet could not be found")
 		return nil, err
 	} else {
@@ -22,7 +22,7 @@ func NewTLSClientCommand() *cobra.Command {
 		Short: "use a TLS client to connect to a server",
 		Long:  ``,
 		Run: func(cmd *cobra.Command, args []string) {
-			queryWithTLS()
+			queryWithTLS(config.GetFromContext(cmd.Context()))
 		},
 	}
 	cmd.Flags().StringVar(&url, "url", "", "url to connect to")
@@ -30,8 +30,10 @@ func NewTLSClientCommand() *cobra.Command {
 }
 
 //nolint:funlen // lots of stuff to do here
-func queryWithTLS() {
-	myTLS, err := config.BuildClientTLSConfig(
+func queryWithTLS(cfg *config.AppConfig) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel() // stops watching the cert files
+	myTLS, err := config.BuildClientTLSConfig(ctx, cfg.TLS,
 		func(c *tls.Config) {
 			//nolint:whitespace // editor/linter issue
 			c.VerifyPeerCertificate = func(
@@ -56,7 +58,7 @@ func queryWithTLS() {
 	}
 
 	req, err := http.NewRequestWithContext(
-		context.Background(),
+		ctx,
 		http.MethodGet,
 		url,
 		http.NoBody)
@@ -64,11 +66,11 @@ func queryWithTLS() {
 		log.Error("error creating request", log.ErrorField(err))
 		return
 	}
-	client := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: myTLS,
-		},
+	transport := &http.Transport{}
+	if myTLS != nil {
+		transport.DialTLSContext = config.NewTLSDialer(myTLS)
 	}
+	client := &http.Client{Transport: transport}
 	resp, err := client.Do(req)
 	if err != nil {
 		log.Error("error executing request", log.ErrorField(err))
@@ -3,7 +3,6 @@ package web
 import (
 	"github.com/spf13/cobra"
 
-	"github.com/mpapenbr/otlpdemo/cmd/config"
 	"github.com/mpapenbr/otlpdemo/cmd/web/grpcclient"
 	"github.com/mpapenbr/otlpdemo/cmd/web/grpcserver"
 	"github.com/mpapenbr/otlpdemo/cmd/web/httpclient"
@@ -16,39 +15,35 @@ func NewWebCommand() *cobra.Command {
 		Short: "collection of web commands",
 		Long:  ``,
 	}
-	cmd.PersistentFlags().StringVar(&config.TLSMinVersion,
-		"tls-min-version",
+	cmd.PersistentFlags().String("tls-min-version",
 		"tls13",
 		"minimum TLS version (e.g., tls13, tls12)")
-	cmd.PersistentFlags().BoolVar(&config.TLSSkipVerify,
-		"tls-skip-verify",
+	cmd.PersistentFlags().Bool("tls-skip-verify",
 		false,
 		"skip verification of server certificate (used for development only)")
-	cmd.PersistentFlags().StringVar(&config.TLSKey,
-		"tls-key",
+	cmd.PersistentFlags().String("tls-key",
 		"",
 		"path to TLS key")
-	cmd.PersistentFlags().StringVar(&config.TLSCert,
-		"tls-cert",
+	cmd.PersistentFlags().String("tls-cert",
 		"",
 		"path to TLS cert")
-	cmd.PersistentFlags().StringSliceVar(&config.TLSCAs,
-		"tls-ca",
+	cmd.PersistentFlags().StringSlice("tls-ca",
 		[]string{},
 		"path to TLS CA certificate to validate server certificate")
-	cmd.PersistentFlags().StringSliceVar(&config.TLSClientCAs,
-		"tls-client-ca",
+	cmd.PersistentFlags().StringSlice("tls-client-ca",
 		[]string{},
 		"path to TLS CA certificate to validate client certificate")
-	cmd.PersistentFlags().StringVar(&config.TLSClientAuth,
-		"tls-client-auth",
+	cmd.PersistentFlags().String("tls-client-auth",
 		"",
 		"how to handle the client cert (none, request, require-and-verify, verify-if-given)")
-	cmd.PersistentFlags().BoolVar(&config.Insecure,
-		"insecure",
+	cmd.PersistentFlags().Bool("insecure",
 		true,
 		"don't use TLS (used for development only)")
 
+	cmd.PersistentFlags().Int("grpc-log-verbosity",
+		0,
+		"verbosity of gRPC internal logging (logged as debug via logger 'grpc')")
+
 	cmd.AddCommand(httpclient.NewJSONPlaceholderCommand())
 	cmd.AddCommand(httpclient.NewTLSClientCommand())
 	cmd.AddCommand(webserver.NewSimpleWebserverCommand())
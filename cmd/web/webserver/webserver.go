@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"math/rand/v2"
+	"net"
 	"net/http"
 	"sync"
 
@@ -16,6 +17,7 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/mpapenbr/otlpdemo/authz"
 	"github.com/mpapenbr/otlpdemo/cmd/config"
 	"github.com/mpapenbr/otlpdemo/log"
 )
@@ -26,10 +28,14 @@ func NewSimpleWebserverCommand() *cobra.Command {
 		Short: "create a simple webserver",
 		Long:  ``,
 		Run: func(cmd *cobra.Command, args []string) {
-			simpleWebserver()
+			simpleWebserver(config.GetFromContext(cmd.Context()))
 		},
 	}
-	cmd.Flags().StringVar(&config.Address, "addr", "localhost:8080", "listen address")
+	cmd.Flags().String("addr", "localhost:8080", "listen address")
+	cmd.Flags().String("authz-policy", "",
+		"policy file to authorize clients by certificate (see README-tls.md)")
+	cmd.Flags().String("debug-addr", "",
+		"listen address for /debug/logs and /debug/tls (plain http, disabled if empty)")
 
 	return &cmd
 }
@@ -73,68 +79,91 @@ var conf map[itemType]itemConfig = map[itemType]itemConfig{
 var tracer = otel.Tracer("webserver")
 
 //nolint:lll // readability
-func simpleWebserver() {
-	fmt.Printf("Starting server on %s\n", config.Address)
-	myTLS, err := config.BuildServerTLSConfig()
+func simpleWebserver(cfg *config.AppConfig) {
+	fmt.Printf("Starting server on %s\n", cfg.Server.Address)
+	myTLS, err := config.BuildServerTLSConfig(context.Background(), cfg.TLS)
 	if err != nil {
 		log.Error("TLS config error", log.ErrorField(err))
 		return
 	}
 
 	mux := http.NewServeMux()
-	addToMux(mux, "/hello", hello(myTLS))
+	addToMux(mux, "/hello", hello(myTLS, cfg.TLS.ClientAuth))
 	addToMux(mux, "/relay/comment", relayComment())
 	addToMux(mux, "/relay/post", relayPost())
 	addToMux(mux, "/relay/photo", relayPhoto())
 	addToMux(mux, "/relay/random", relayRandom())
 	addToMux(mux, "/relay/sequence", relaySequence())
 	addToMux(mux, "/relay/concurrent", relayConcurrent())
-	mainHander := otelhttp.NewHandler(mux, "oteldemo-webserver",
+	if cfg.Server.DebugAddress != "" {
+		go debugServer(cfg.Server.DebugAddress)
+	}
+	var handler http.Handler = mux
+	if cfg.Server.AuthzPolicy != "" {
+		policy, err := authz.LoadPolicy(cfg.Server.AuthzPolicy)
+		if err != nil {
+			log.Error("authz policy error", log.ErrorField(err))
+			return
+		}
+		// called after the otelhttp handler to add the decision to the span
+		handler = authz.HTTPMiddleware(policy)(mux)
+	}
+	mainHander := otelhttp.NewHandler(handler, "oteldemo-webserver",
 		otelhttp.WithMessageEvents(
 			otelhttp.ReadEvents,
 			otelhttp.WriteEvents))
-	if config.Insecure {
+	if cfg.TLS.Insecure {
 		log.Info("Using insecure mode with http")
-		if err = http.ListenAndServe(config.Address, mainHander); err != nil {
+		if err = http.ListenAndServe(cfg.Server.Address, mainHander); err != nil {
 			log.Error("Error starting http server", log.ErrorField(err))
 			return
 		}
 	} else {
 		log.Info("TLS config present. Server accepts TLS connections only")
+		var l net.ListenConfig
+		lis, err := l.Listen(context.Background(), "tcp", cfg.Server.Address)
+		if err != nil {
+			log.Error("error starting listener", log.ErrorField(err))
+			return
+		}
 		server := &http.Server{
-			Addr:      config.Address,
-			Handler:   mainHander,
-			TLSConfig: myTLS,
+			Addr:    cfg.Server.Address,
+			Handler: mainHander,
 		}
-		if err = server.ListenAndServeTLS(config.TLSCert, config.TLSKey); err != nil {
+		// the handshakes are done (and recorded) by the listener
+		if err = server.Serve(config.NewTLSListener(lis, myTLS)); err != nil {
 			log.Error("Error starting TLS server", log.ErrorField(err))
 			return
 		}
 	}
 }
 
+// debugServer serves the debug endpoints on a separate listener, so they are
+// not exposed on the public address
+func debugServer(addr string) {
+	mux := http.NewServeMux()
+	// not wrapped by the logging middleware, it would add entries to the buffer
+	mux.Handle("/debug/logs", log.NewRingBufferHandler(log.Default().RingBuffer()))
+	mux.Handle("/debug/tls", config.NewTLSStatusHandler())
+	log.Info("Serving debug endpoints", log.String("addr", addr))
+	//nolint:gosec // only meant for a local address
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Error("Error starting debug server", log.ErrorField(err))
+	}
+}
+
 func addToMux(mux *http.ServeMux, pattern string, handler http.Handler) {
 	mux.Handle(pattern,
 		TraceIDMiddleware(LoggingMiddleware(handler)))
 }
 
+// LoggingMiddleware puts a request logger into the context (see log.HTTPMiddleware)
 func LoggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fields := []log.Field{
-			log.String("method", r.Method),
-			log.String("url", r.URL.String()),
-			log.String("remoteAddr", r.RemoteAddr),
-		}
-		span := trace.SpanFromContext(r.Context())
-		if span.SpanContext().HasTraceID() {
-			fields = append(fields,
-				log.Any("ctx", r.Context()),
-				log.String("trace_id", span.SpanContext().TraceID().String()))
-		}
-		log.Debug("Request received", fields...)
-
-		next.ServeHTTP(w, r)
-	})
+	return log.HTTPMiddleware(log.Default())(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			log.GetFromContext(r.Context()).Debug("Request received")
+			next.ServeHTTP(w, r)
+		}))
 }
 
 func TraceIDMiddleware(next http.Handler) http.Handler {
@@ -147,18 +176,19 @@ func TraceIDMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-func hello(myTLS *tls.Config) http.HandlerFunc {
+func hello(myTLS *tls.Config, clientAuthMode string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		logger := log.GetFromContext(r.Context())
 		if r.TLS == nil {
-			log.Debug("No TLS connection")
+			logger.Debug("No TLS connection")
 			fmt.Fprint(w, "Hello, world! (no TLS)\n")
 			return
 		}
 		showPeers := func(msg string) {
-			log.Debug(msg,
+			logger.Debug(msg,
 				log.Int("peerCerts", len(r.TLS.PeerCertificates)))
 			for i := 0; i < len(r.TLS.PeerCertificates); i++ {
-				log.Debug("Client cert",
+				logger.Debug("Client cert",
 					log.String("subject", r.TLS.PeerCertificates[i].Subject.String()),
 					log.String("issuer", r.TLS.PeerCertificates[i].Issuer.String()),
 				)
@@ -167,7 +197,7 @@ func hello(myTLS *tls.Config) http.HandlerFunc {
 				msg,
 				len(r.TLS.PeerCertificates))
 		}
-		clientAuth, _ := config.ParseClientAuth(config.TLSClientAuth)
+		clientAuth, _ := config.ParseClientAuth(clientAuthMode)
 		switch clientAuth {
 		case tls.NoClientCert:
 			fmt.Fprint(w, "Hello, world! (no client cert required)\n")
@@ -185,7 +215,7 @@ func hello(myTLS *tls.Config) http.HandlerFunc {
 			showPeers("verify client cert if given")
 
 		default:
-			log.Debug("Unknown client auth mode",
+			logger.Debug("Unknown client auth mode",
 				log.String("mode", myTLS.ClientAuth.String()))
 			http.Error(w, "Hello, world! (unknown client auth mode)",
 				http.StatusUnauthorized)
@@ -0,0 +1,259 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MergeFile decodes the yaml file onto c. Only the given values are changed,
+// entries of loggers are replaced as a whole.
+// If section is not empty, only the value of this top level key is used.
+// This way the config may be part of another config file.
+// The values are validated like in LoadConfig.
+func (c *Config) MergeFile(filename, section string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	if len(root.Content) == 0 {
+		return nil // empty file
+	}
+	node := root.Content[0]
+	if section != "" {
+		if node = mappingValue(node, section); node == nil {
+			return nil
+		}
+	}
+	if err := c.decodeNode(node); err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	return nil
+}
+
+// decodeNode decodes node strictly. Unknown keys, invalid levels, logger
+// patterns and filter rules are reported together with their line numbers.
+func (c *Config) decodeNode(node *yaml.Node) error {
+	errs := unknownKeys(node, reflect.TypeFor[Config](), "")
+	if err := node.Decode(c); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return err
+		}
+		for _, msg := range typeErr.Errors {
+			errs = append(errs, errors.New(msg))
+		}
+	}
+	errs = append(errs, validateConfig(node)...)
+	return errors.Join(errs...)
+}
+
+// ApplyEnv overrides values of c by environment variables starting with prefix.
+// The rest of the variable name is the path of yaml keys separated by
+// underscores, case is ignored, e.g. <prefix>DEFAULTLEVEL or <prefix>ZAP_ENCODING.
+// Within loggers the underscores of the logger name stand for dots:
+// <prefix>LOGGERS_DB_OTELLEVEL=debug, <prefix>LOGGERS_WEB_HANDLER=debug.
+// Logger names which are not configured yet are used in lower case, they are
+// matched ignoring case (e.g. LOGGERS_DEMOLOGGER configures demoLogger).
+// Lists are separated by spaces. The result is checked by Validate.
+func (c *Config) ApplyEnv(prefix string, environ []string) error {
+	var errs []error
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || len(name) <= len(prefix) ||
+			!strings.EqualFold(name[:len(prefix)], prefix) {
+
+			continue
+		}
+		path := strings.Split(name[len(prefix):], "_")
+		if err := setByPath(reflect.ValueOf(c).Elem(), path, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	if len(errs) == 0 {
+		// the values are checked like those of the files
+		if err := c.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("after applying %s*: %w", prefix, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// unknownKeys reports keys of mappings in node which have no matching field
+// in the corresponding struct type t
+func unknownKeys(node *yaml.Node, t reflect.Type, path string) []error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	var errs []error
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if t.Kind() == reflect.Map {
+			errs = append(errs, unknownKeys(value, t.Elem(), joinPath(path, key.Value))...)
+			continue
+		}
+		if t.Kind() != reflect.Struct {
+			continue
+		}
+		f, ok := yamlField(t, key.Value, false)
+		if !ok {
+			if path == "" {
+				errs = append(errs, fmt.Errorf("line %d: unknown key %q",
+					key.Line, key.Value))
+			} else {
+				errs = append(errs, fmt.Errorf("line %d: %s: unknown key %q",
+					key.Line, path, key.Value))
+			}
+			continue
+		}
+		errs = append(errs, unknownKeys(value, f.Type, joinPath(path, key.Value))...)
+	}
+	return errs
+}
+
+// setByPath decodes value into the field of v given by the yaml keys of path
+func setByPath(v reflect.Value, path []string, value string) error {
+	if len(path) == 0 {
+		return decodeEnvValue(v, value)
+	}
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Struct {
+		f, ok := yamlField(v.Type(), path[0], true)
+		if !ok {
+			return fmt.Errorf("unknown key %q", path[0])
+		}
+		fv := v.FieldByIndex(f.Index)
+		if len(path) == 1 && fv.Kind() == reflect.String &&
+			strings.HasSuffix(strings.ToLower(path[0]), "level") && value != "" {
+
+			if _, err := ParseLevel(value); err != nil {
+				return fmt.Errorf("invalid level %q", value)
+			}
+		}
+		return setByPath(fv, path[1:], value)
+	}
+	if v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String {
+		// the map key may contain underscores (dots), the shortest key which
+		// leaves a valid path for the element is used
+		for n := 1; n <= len(path); n++ {
+			if !validPath(v.Type().Elem(), path[n:]) {
+				continue
+			}
+			key := mapKey(v, strings.Join(path[:n], "."))
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if old := v.MapIndex(key); old.IsValid() {
+				elem.Set(old)
+			}
+			if err := setByPath(elem, path[n:], value); err != nil {
+				return err
+			}
+			if v.IsNil() {
+				v.Set(reflect.MakeMap(v.Type()))
+			}
+			v.SetMapIndex(key, elem)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown key %q", path[0])
+}
+
+// validPath reports if path leads to a field of t
+func validPath(t reflect.Type, path []string) bool {
+	if len(path) == 0 {
+		return true
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Map {
+		return true
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	f, ok := yamlField(t, path[0], true)
+	return ok && validPath(f.Type, path[1:])
+}
+
+// decodeEnvValue decodes value like a plain yaml scalar into v.
+// Slices are filled with the space separated parts of value.
+func decodeEnvValue(v reflect.Value, value string) error {
+	node := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		node = &yaml.Node{Kind: yaml.SequenceNode}
+		for _, item := range strings.Fields(value) {
+			node.Content = append(node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: item})
+		}
+	}
+	return node.Decode(v.Addr().Interface())
+}
+
+// mapKey returns the existing key of map v which equals name ignoring case.
+// If there is none, name in lower case is returned.
+func mapKey(v reflect.Value, name string) reflect.Value {
+	for _, k := range v.MapKeys() {
+		if strings.EqualFold(k.String(), name) {
+			return k
+		}
+	}
+	return reflect.ValueOf(strings.ToLower(name)).Convert(v.Type().Key())
+}
+
+// yamlField returns the field of struct type t decoded from key.
+// Fields of inlined structs are included.
+//
+//nolint:whitespace // editor/linter issue
+func yamlField(
+	t reflect.Type,
+	key string,
+	ignoreCase bool,
+) (reflect.StructField, bool) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(opts, "inline") && f.Type.Kind() == reflect.Struct {
+			if inner, ok := yamlField(f.Type, key, ignoreCase); ok {
+				inner.Index = append([]int{i}, inner.Index...)
+				return inner, true
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name) // default of yaml.v3
+		}
+		if name == key || (ignoreCase && strings.EqualFold(name, key)) {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,45 @@
+package log
+
+import "testing"
+
+func TestApplyEnvLoggerNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		loggers map[string]LoggerConfig
+		env     string
+		logger  string // name passed to Named
+		want    Level
+	}{
+		{
+			"new logger", nil,
+			"OTLPDEMO_LOG_LOGGERS_DEMOLOGGER=debug", "demoLogger", DebugLevel,
+		},
+		{
+			"configured logger", map[string]LoggerConfig{"demoLogger": {Level: "info"}},
+			"OTLPDEMO_LOG_LOGGERS_DEMOLOGGER=debug", "demoLogger", DebugLevel,
+		},
+		{
+			"nested logger", nil,
+			"OTLPDEMO_LOG_LOGGERS_WEB_HANDLER=error", "web.Handler", ErrorLevel,
+		},
+		{
+			"regex segment", map[string]LoggerConfig{"db.[a-z]+": {Level: "warn"}},
+			"OTLPDEMO_LOG_DEFAULTLEVEL=info", "db.Pool", WarnLevel,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultDevConfig()
+			cfg.Zap.OutputPaths = []string{"/dev/null"}
+			cfg.Loggers = tt.loggers
+			if err := cfg.ApplyEnv("OTLPDEMO_LOG_", []string{tt.env}); err != nil {
+				t.Fatal(err)
+			}
+			l := New(WithLogConfig(cfg))
+			if got := l.Named(tt.logger).Level(); got != tt.want {
+				t.Errorf("level of %s = %s, want %s (loggers %v)",
+					tt.logger, got, tt.want, cfg.Loggers)
+			}
+		})
+	}
+}
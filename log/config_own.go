@@ -10,6 +10,7 @@ type (
 	loggerConfig struct {
 		cfg                 *Config
 		level               string          // optional, if empty, use default level from config
+		otelLevel           string          // optional, if empty, use otel level from config
 		telemetry           *otel.Telemetry // optional, if nil, no otel logging
 		removeContextFields bool            // if true, remove context fields from the log
 		useZap              bool            // if true, use configured zap
@@ -35,6 +36,9 @@ func newLoggerConfig(opts ...ConfigOption) *loggerConfig {
 	if ret.level == "" {
 		ret.level = ret.cfg.DefaultLevel
 	}
+	if ret.otelLevel == "" {
+		ret.otelLevel = ret.cfg.OtelLevel
+	}
 	return ret
 }
 
@@ -52,6 +56,15 @@ func WithLogLevel(arg string) ConfigOption {
 	})
 }
 
+// sets the threshold for OTLP output independent of the console level
+// if neither this nor the config provide a value, the console level is used
+func WithOtelLogLevel(arg string) ConfigOption {
+	return optFunc(func(c *loggerConfig) *loggerConfig {
+		c.otelLevel = arg
+		return c
+	})
+}
+
 func WithTelemetry(arg *otel.Telemetry) ConfigOption {
 	return optFunc(func(c *loggerConfig) *loggerConfig {
 		c.telemetry = arg
@@ -0,0 +1,131 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"moul.io/zapfilter"
+)
+
+// top level keys which contain a level
+var levelKeys = []string{"defaultLevel", "otelLevel", "spanEventLevel"}
+
+// validateConfig checks the values of the config that are not checked by
+// decoding the yaml. The returned errors contain the line numbers.
+func validateConfig(root *yaml.Node) []error {
+	if root.Kind != yaml.MappingNode {
+		return nil
+	}
+	var errs []error
+	for _, key := range levelKeys {
+		if node := mappingValue(root, key); node != nil {
+			errs = append(errs, checkLevel(node, key)...)
+		}
+	}
+	if ring := mappingValue(root, "ringBuffer"); ring != nil {
+		if node := mappingValue(ring, "level"); node != nil {
+			errs = append(errs, checkLevel(node, "ringBuffer.level")...)
+		}
+	}
+	if loggers := mappingValue(root, "loggers"); loggers != nil &&
+		loggers.Kind == yaml.MappingNode {
+
+		for i := 0; i+1 < len(loggers.Content); i += 2 {
+			errs = append(errs,
+				checkLoggerEntry(loggers.Content[i], loggers.Content[i+1])...)
+		}
+	}
+	if filters := mappingValue(root, "filters"); filters != nil &&
+		filters.Kind == yaml.SequenceNode {
+
+		for _, item := range filters.Content {
+			if _, err := zapfilter.ParseRules(item.Value); err != nil {
+				errs = append(errs, fmt.Errorf("line %d: invalid filter rule %q: %w",
+					item.Line, item.Value, err))
+			}
+		}
+	}
+	return errs
+}
+
+// Validate checks the values of c like validateConfig does for the yaml.
+// It is used for values which are not read from a file (e.g. env vars).
+func (c *Config) Validate() error {
+	var errs []error
+	levels := map[string]string{
+		"defaultLevel":   c.DefaultLevel,
+		"otelLevel":      c.OtelLevel,
+		"spanEventLevel": c.SpanEventLevel,
+	}
+	if c.RingBuffer != nil {
+		levels["ringBuffer.level"] = c.RingBuffer.Level
+	}
+	for name, lc := range c.Loggers {
+		if _, err := compileLoggerPattern(name); err != nil {
+			errs = append(errs, fmt.Errorf("invalid logger pattern %q: %w", name, err))
+		}
+		levels["loggers."+name+".level"] = lc.Level
+		levels["loggers."+name+".otelLevel"] = lc.OtelLevel
+	}
+	for _, path := range slices.Sorted(maps.Keys(levels)) {
+		if v := levels[path]; strings.TrimSpace(v) != "" {
+			if _, err := ParseLevel(v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid level %q", path, v))
+			}
+		}
+	}
+	for _, filter := range c.Filters {
+		if _, err := zapfilter.ParseRules(filter); err != nil {
+			errs = append(errs, fmt.Errorf("invalid filter rule %q: %w", filter, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func checkLoggerEntry(key, value *yaml.Node) []error {
+	var errs []error
+	if _, err := compileLoggerPattern(key.Value); err != nil {
+		errs = append(errs, fmt.Errorf("line %d: invalid logger pattern %q: %w",
+			key.Line, key.Value, err))
+	}
+	switch value.Kind {
+	case yaml.ScalarNode:
+		errs = append(errs, checkLevel(value, "loggers."+key.Value)...)
+	case yaml.MappingNode:
+		for _, lvlKey := range []string{"level", "otelLevel"} {
+			if node := mappingValue(value, lvlKey); node != nil {
+				errs = append(errs,
+					checkLevel(node, "loggers."+key.Value+"."+lvlKey)...)
+			}
+		}
+	case yaml.DocumentNode, yaml.SequenceNode, yaml.AliasNode:
+		errs = append(errs, fmt.Errorf("line %d: loggers.%s: expected level or mapping",
+			value.Line, key.Value))
+	}
+	return errs
+}
+
+func checkLevel(node *yaml.Node, path string) []error {
+	if node.Kind != yaml.ScalarNode || strings.TrimSpace(node.Value) == "" {
+		return nil
+	}
+	if _, err := ParseLevel(node.Value); err != nil {
+		return []error{fmt.Errorf("line %d: %s: invalid level %q",
+			node.Line, path, node.Value)}
+	}
+	return nil
+}
+
+// mappingValue returns the value node for key or nil if key is not present
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
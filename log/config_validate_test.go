@@ -0,0 +1,108 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMergeFileReportsLines(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string // substrings of the error, none: valid
+	}{
+		{"valid", "defaultLevel: debug\nloggers:\n  db: warn\nfilters:\n  - '*:db'\n", nil},
+		{"default level", "defaultLevel: verbose\n", []string{
+			`line 1: defaultLevel: invalid level "verbose"`,
+		}},
+		{"levels", "otelLevel: info\nspanEventLevel: loud\nringBuffer:\n  level: x\n",
+			[]string{
+				`line 2: spanEventLevel: invalid level "loud"`,
+				`line 4: ringBuffer.level: invalid level "x"`,
+			}},
+		{"logger entries", "loggers:\n  db: verbose\n  web:\n    otelLevel: x\n" +
+			"  'db.[': info\n  grpc: [info]\n", []string{
+			`line 2: loggers.db: invalid level "verbose"`,
+			`line 4: loggers.web.otelLevel: invalid level "x"`,
+			`line 5: invalid logger pattern "db.["`,
+			`line 6: loggers.grpc: expected level or mapping`,
+		}},
+		{"filter", "filters:\n  - '*:db'\n  - 'verbose:db'\n", []string{
+			`line 3: invalid filter rule "verbose:db"`,
+		}},
+		{"unknown key", "defaultLevl: info\n", []string{"defaultLevl"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "logger.yml")
+			if err := os.WriteFile(file, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			err := DefaultDevConfig().MergeFile(file, "")
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected errors %q", tt.want)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q doesn't contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyEnvValidates(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+		want string // substring of the error, empty: valid
+	}{
+		{"valid level", "OTLPDEMO_LOG_DEFAULTLEVEL=debug", ""},
+		{"invalid level", "OTLPDEMO_LOG_DEFAULTLEVEL=verbose", `invalid level "verbose"`},
+		{"invalid logger level", "OTLPDEMO_LOG_LOGGERS_DB=verbose",
+			`loggers.db.level: invalid level "verbose"`},
+		{"invalid logger pattern", "OTLPDEMO_LOG_LOGGERS_DB[=info",
+			`invalid logger pattern "db["`},
+		{"invalid filter", "OTLPDEMO_LOG_FILTERS=*:db verbose:db",
+			`invalid filter rule "verbose:db"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DefaultDevConfig().ApplyEnv("OTLPDEMO_LOG_", []string{tt.env})
+			switch {
+			case tt.want == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Fatalf("error %v doesn't contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewWarnsOnInvalidFilters(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "out.log")
+	cfg := DefaultDevConfig()
+	cfg.Zap.OutputPaths = []string{file}
+	cfg.Filters = []string{"verbose:db"}
+	l := New(WithLogConfig(cfg))
+	l.Info("not filtered")
+	//nolint:errcheck // by design
+	l.Sync()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Ignoring invalid log filters", "not filtered"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("output %q doesn't contain %q", data, want)
+		}
+	}
+}
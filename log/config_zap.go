@@ -1,21 +1,75 @@
 package log
 
 import (
-	"os"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/yaml.v3"
 )
 
-// this config is used to configure the zap logger by yaml file
-// additional to the zap config, it contains a default level and a map of named loggers
-// with theire respective levels. These level have precedence over the default level.
-type Config struct {
-	DefaultLevel string            `yaml:"defaultLevel"`
-	Loggers      map[string]string `yaml:"loggers"`
-	Zap          zap.Config        `yaml:"zap"`
-	Filters      []string          `yaml:"filters"`
+type (
+	// this config is used to configure the zap logger by yaml file
+	// additional to the zap config, it contains a default level and a map of named
+	// loggers with theire respective levels. These level have precedence over the
+	// default level. The OTLP output may use a different threshold (otelLevel).
+	Config struct {
+		DefaultLevel string                  `yaml:"defaultLevel"`
+		OtelLevel    string                  `yaml:"otelLevel"` // empty: console level
+		Loggers      map[string]LoggerConfig `yaml:"loggers"`
+		Zap          zap.Config              `yaml:"zap"`
+		Filters      []string                `yaml:"filters"`
+		// sampling and rate limit are used for all loggers without own settings
+		Sampling   *SamplingConfig  `yaml:"sampling"`
+		RateLimit  *RateLimitConfig `yaml:"rateLimit"`
+		DropReport DropReportConfig `yaml:"dropReport"`
+		// entries with at least this level are added as events to the current span
+		// (empty: disabled)
+		SpanEventLevel string `yaml:"spanEventLevel"`
+		// count written entries by level and logger via OTel (log.entries)
+		EntryMetric bool `yaml:"entryMetric"`
+		// keep the last entries in memory (nil: disabled)
+		RingBuffer *RingBufferConfig `yaml:"ringBuffer"`
+	}
+	// config for a named logger. If only the level is needed it may be
+	// configured as plain value, e.g. `demoLogger: debug`
+	LoggerConfig struct {
+		Level     string           `yaml:"level"`
+		OtelLevel string           `yaml:"otelLevel"` // empty: Level
+		Sampling  *SamplingConfig  `yaml:"sampling"`
+		RateLimit *RateLimitConfig `yaml:"rateLimit"`
+	}
+	// zap-style sampling: per tick the first Initial entries with the same level
+	// and message are logged, after that only every Thereafter-th entry
+	SamplingConfig struct {
+		Tick       time.Duration `yaml:"tick"`
+		Initial    int           `yaml:"initial"`
+		Thereafter int           `yaml:"thereafter"`
+	}
+	// token bucket: PerSecond entries are allowed in average, Burst entries at once
+	RateLimitConfig struct {
+		PerSecond float64 `yaml:"perSecond"`
+		Burst     int     `yaml:"burst"`
+	}
+	// the ring buffer keeps the last Size entries. If Level is empty, all
+	// entries written by the logger are kept.
+	RingBufferConfig struct {
+		Size  int    `yaml:"size"`
+		Level string `yaml:"level"`
+	}
+	// controls how entries dropped by sampling or rate limit are reported
+	DropReportConfig struct {
+		Interval time.Duration `yaml:"interval"` // log dropped counts, 0 disables
+		Metric   bool          `yaml:"metric"`   // count dropped entries via OTel
+	}
+)
+
+func (c *LoggerConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&c.Level)
+	}
+	type plain LoggerConfig // prevent recursion
+	return value.Decode((*plain)(c))
 }
 
 func DefaultDevConfig() *Config {
@@ -35,17 +89,11 @@ func DefaultProdConfig() *Config {
 }
 
 func LoadConfig(filename string) (*Config, error) {
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, err
-	}
-
 	cfg := Config{
 		Zap: zap.NewProductionConfig(),
 	}
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	if err := cfg.MergeFile(filename, ""); err != nil {
 		return nil, err
 	}
-
 	return &cfg, nil
 }
@@ -0,0 +1,81 @@
+package log
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap/zapcore"
+)
+
+type (
+	// entryCounter counts the written log entries per level and logger name
+	entryCounter struct {
+		counter metric.Int64Counter
+		opts    sync.Map // entryKey -> metric.AddOption
+	}
+	entryKey struct {
+		level  Level
+		logger string
+	}
+	// entryCounterCore is added to the cores of a logger. It is enabled whenever
+	// one of the other cores is enabled, so disabled entries cost no more than
+	// the level check of these cores.
+	entryCounterCore struct {
+		zapcore.LevelEnabler
+		entries *entryCounter
+	}
+)
+
+// returns nil if the counter is not enabled or could not be created
+func newEntryCounter(enabled bool) *entryCounter {
+	if !enabled {
+		return nil
+	}
+	counter, err := otel.Meter("otlpdemo/log").Int64Counter("log.entries",
+		metric.WithDescription("Number of written log entries"),
+		metric.WithUnit("{entry}"))
+	if err != nil {
+		return nil
+	}
+	return &entryCounter{counter: counter}
+}
+
+func (e *entryCounter) add(level Level, logger string) {
+	key := entryKey{level: level, logger: logger}
+	opt, ok := e.opts.Load(key)
+	if !ok {
+		// attribute sets are cached, creating them is the expensive part
+		opt, _ = e.opts.LoadOrStore(key, metric.WithAttributeSet(attribute.NewSet(
+			attribute.String("level", level.String()),
+			attribute.String("logger", logger))))
+	}
+	//nolint:errcheck // by design
+	e.counter.Add(context.Background(), 1, opt.(metric.AddOption))
+}
+
+func (c *entryCounterCore) With(fields []zapcore.Field) zapcore.Core {
+	return c
+}
+
+//nolint:whitespace // editor/linter issue
+func (c *entryCounterCore) Check(
+	ent zapcore.Entry,
+	ce *zapcore.CheckedEntry,
+) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *entryCounterCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	c.entries.add(ent.Level, ent.LoggerName)
+	return nil
+}
+
+func (c *entryCounterCore) Sync() error {
+	return nil
+}
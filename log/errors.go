@@ -0,0 +1,308 @@
+package log
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// maximum number of causes collected from an error chain
+const maxErrorCauses = 32
+
+type (
+	// stackError is created by NewErrorf and keeps the stack of its creation
+	stackError struct {
+		err   error
+		stack []uintptr
+	}
+	// fieldError is used by ErrorField. The verbose output (%+v) contains the
+	// causes and the stack, zap logs it as errorVerbose if it differs from the
+	// message.
+	fieldError struct {
+		err error
+	}
+	// fieldErrorGroup is used by ErrorField for errors with several causes
+	// (multierr, errors.Join). It keeps Errors(), zap logs them as errorCauses.
+	fieldErrorGroup struct {
+		*fieldError
+	}
+	errorCause struct {
+		errType string
+		msg     string
+	}
+)
+
+// NewErrorf works like fmt.Errorf. Additionally the stack is captured,
+// it is logged by ErrorField as exception.stacktrace.
+func NewErrorf(format string, args ...any) error {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	return &stackError{err: fmt.Errorf(format, args...), stack: pcs[:n]}
+}
+
+func (e *stackError) Error() string {
+	return e.err.Error()
+}
+
+func (e *stackError) Unwrap() error {
+	return e.err
+}
+
+// stackTrace formats the stack like runtime/debug.Stack
+func (e *stackError) stackTrace() string {
+	var sb strings.Builder
+	frames := runtime.CallersFrames(e.stack)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&sb, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return sb.String()
+}
+
+// ErrorField creates the field for err with key "error".
+// Besides the message the chain of causes (errors.Unwrap, errors.Join) with
+// their types and the stack of errors created by NewErrorf are logged.
+// For OTLP these are mapped to the exception attributes.
+// The errors of groups (multierr, errors.Join) are logged as errorCauses.
+func ErrorField(err error) Field {
+	if err == nil {
+		return zap.Skip()
+	}
+	fe := &fieldError{err: err}
+	//nolint:errorlint // only the direct type is of interest
+	switch err.(type) {
+	case interface{ Errors() []error }, interface{ Unwrap() []error }:
+		return zap.Error(&fieldErrorGroup{fieldError: fe})
+	}
+	return zap.Error(fe)
+}
+
+// Errors returns the causes of the wrapped group
+func (e *fieldErrorGroup) Errors() []error {
+	//nolint:errorlint // only the direct type is of interest
+	switch g := e.err.(type) {
+	case interface{ Errors() []error }:
+		return g.Errors()
+	case interface{ Unwrap() []error }:
+		return g.Unwrap()
+	}
+	return nil
+}
+
+// unwrapFieldError returns the error passed to ErrorField
+func unwrapFieldError(err error) error {
+	//nolint:errorlint // only the direct type is of interest
+	switch e := err.(type) {
+	case *fieldError:
+		return e.err
+	case *fieldErrorGroup:
+		return e.err
+	}
+	return err
+}
+
+func (e *fieldError) Error() string {
+	return e.err.Error()
+}
+
+func (e *fieldError) Unwrap() error {
+	return e.err
+}
+
+func (e *fieldError) Format(s fmt.State, verb rune) {
+	if verb != 'v' || !s.Flag('+') {
+		fmt.Fprint(s, e.err.Error())
+		return
+	}
+	causes := errorCauses(e.err)
+	stack := errorStack(e.err)
+	if len(causes) < 2 && stack == "" {
+		fmt.Fprint(s, e.err.Error()) // nothing to add
+		return
+	}
+	fmt.Fprint(s, e.err.Error())
+	for _, c := range formatCauses(causes) {
+		fmt.Fprintf(s, "\ncause %s", c)
+	}
+	if stack != "" {
+		fmt.Fprintf(s, "\nstack:\n%s", stack)
+	}
+}
+
+// errorCauses returns type and message of the errors in the chain of err.
+// Wrappers created by NewErrorf or ErrorField are skipped.
+func errorCauses(err error) []errorCause {
+	var ret []errorCause
+	for _, e := range errorChain(err) {
+		//nolint:errorlint // only the direct type is of interest
+		switch e.(type) {
+		case *stackError, *fieldError, *fieldErrorGroup:
+			continue
+		}
+		ret = append(ret, errorCause{errType: errorType(e), msg: e.Error()})
+	}
+	return ret
+}
+
+func formatCauses(causes []errorCause) []string {
+	ret := make([]string, len(causes))
+	for i, c := range causes {
+		ret[i] = c.errType + ": " + c.msg
+	}
+	return ret
+}
+
+// errorStack returns the stack of the innermost error created by NewErrorf
+func errorStack(err error) string {
+	var ret string
+	for _, c := range errorChain(err) {
+		//nolint:errorlint // only the direct type is of interest
+		if se, ok := c.(*stackError); ok {
+			ret = se.stackTrace()
+		}
+	}
+	return ret
+}
+
+// errorChain returns all errors reachable from err (depth first)
+func errorChain(err error) []error {
+	var ret []error
+	var walk func(err error)
+	walk = func(err error) {
+		for err != nil && len(ret) < maxErrorCauses {
+			ret = append(ret, err)
+			//nolint:errorlint // walking the chain manually
+			switch u := err.(type) {
+			case interface{ Unwrap() error }:
+				err = u.Unwrap()
+			case interface{ Unwrap() []error }:
+				for _, inner := range u.Unwrap() {
+					walk(inner)
+				}
+				return
+			default:
+				return
+			}
+		}
+	}
+	walk(err)
+	return ret
+}
+
+// rootCause follows the chain of err to its end, ignoring the wrappers
+// of this package. For joined errors the first one is used.
+func rootCause(err error) error {
+	ret := err
+	for i := 0; err != nil && i < maxErrorCauses; i++ {
+		//nolint:errorlint // only the direct type is of interest
+		switch e := err.(type) {
+		case *stackError, *fieldError, *fieldErrorGroup:
+		default:
+			ret = e
+		}
+		//nolint:errorlint // walking the chain manually
+		switch u := err.(type) {
+		case interface{ Unwrap() error }:
+			err = u.Unwrap()
+		case interface{ Unwrap() []error }:
+			if inner := u.Unwrap(); len(inner) > 0 {
+				err = inner[0]
+			} else {
+				err = nil
+			}
+		default:
+			err = nil
+		}
+	}
+	return ret
+}
+
+// errorType returns the type name like the OTel SDK does for exception.type
+func errorType(err error) string {
+	t := reflect.TypeOf(err)
+	if t == nil {
+		return ""
+	}
+	ptr := ""
+	if t.Kind() == reflect.Pointer {
+		ptr = "*"
+		t = t.Elem()
+	}
+	if t.PkgPath() != "" && t.Name() != "" {
+		return ptr + t.PkgPath() + "." + t.Name()
+	}
+	return ptr + t.String()
+}
+
+// exceptionFields creates the OTLP exception attributes for err as fields.
+// exception.type is the type of the root cause, it is the most specific one.
+// For joined errors the first one is followed.
+func exceptionFields(err error) (fields []Field, hasStack bool) {
+	err = unwrapFieldError(err)
+	causes := errorCauses(err)
+	fields = []Field{String(string(semconv.ExceptionMessageKey), err.Error())}
+	fields = append(fields,
+		String(string(semconv.ExceptionTypeKey), errorType(rootCause(err))))
+	if len(causes) > 1 {
+		fields = append(fields, zap.Strings("exception.causes", formatCauses(causes)))
+	}
+	if stack := errorStack(err); stack != "" {
+		fields = append(fields, String(string(semconv.ExceptionStacktraceKey), stack))
+		hasStack = true
+	}
+	return fields, hasStack
+}
+
+// exceptionCore adds the exception attributes for the first error field to the
+// entries passed to the otelzap core. The original error is still passed
+// to the otelzap core, so the record contains it too.
+type exceptionCore struct {
+	zapcore.Core
+}
+
+func (c *exceptionCore) With(fields []zapcore.Field) zapcore.Core {
+	return &exceptionCore{Core: c.Core.With(fields)}
+}
+
+//nolint:whitespace // editor/linter issue
+func (c *exceptionCore) Check(
+	ent zapcore.Entry,
+	ce *zapcore.CheckedEntry,
+) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *exceptionCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	for i := range fields {
+		if fields[i].Type != zapcore.ErrorType {
+			continue
+		}
+		err, ok := fields[i].Interface.(error)
+		if !ok || err == nil {
+			continue
+		}
+		extra, hasStack := exceptionFields(err)
+		// the fields are shared with the other cores, don't modify them
+		newFields := make([]zapcore.Field, 0, len(fields)+len(extra))
+		newFields = append(newFields, fields...)
+		newFields[i].Interface = unwrapFieldError(err)
+		if hasStack {
+			// the stack of the error creation is more helpful than the stack of
+			// the log call, otherwise otelzap adds it as exception.stacktrace too
+			ent.Stack = ""
+		}
+		return c.Core.Write(ent, append(newFields, extra...))
+	}
+	return c.Core.Write(ent, fields)
+}
@@ -0,0 +1,69 @@
+package log
+
+import (
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// errorGroup behaves like the errors of go.uber.org/multierr
+type errorGroup []error
+
+func (g errorGroup) Error() string {
+	return errors.Join(g...).Error()
+}
+
+func (g errorGroup) Errors() []error {
+	return g
+}
+
+func TestErrorFieldKeepsErrorGroups(t *testing.T) {
+	first, second := errors.New("first"), errors.New("second")
+	tests := []struct {
+		name       string
+		err        error
+		wantCauses int
+	}{
+		{"errorGroup", errorGroup{first, second}, 2},
+		{"errors.Join", errors.Join(first, second), 2},
+		{"single", NewErrorf("wrapped: %w", first), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			zap.New(core).Error("failed", ErrorField(tt.err))
+			ctx := logs.All()[0].ContextMap()
+			if ctx["error"] != tt.err.Error() {
+				t.Errorf("error = %q, want %q", ctx["error"], tt.err.Error())
+			}
+			causes, _ := ctx["errorCauses"].([]interface{})
+			if len(causes) != tt.wantCauses {
+				t.Errorf("errorCauses = %v, want %d entries", ctx["errorCauses"],
+					tt.wantCauses)
+			}
+			if tt.wantCauses == 0 && ctx["errorVerbose"] == nil {
+				t.Error("expected errorVerbose with the stack")
+			}
+		})
+	}
+}
+
+func TestErrorFieldKeepsChain(t *testing.T) {
+	err := errors.Join(errors.New("first"), errors.New("second"))
+	f := ErrorField(err)
+	//nolint:errorlint,errcheck // the wrapper is tested
+	wrapped := f.Interface.(error)
+	if !errors.Is(wrapped, err) {
+		t.Error("errors.Is should find the wrapped error")
+	}
+	if got := unwrapFieldError(wrapped); got != err { //nolint:errorlint // identity
+		t.Errorf("unwrapFieldError = %v, want %v", got, err)
+	}
+	causes := errorCauses(wrapped)
+	if len(causes) != 3 {
+		t.Errorf("causes = %v, want the group and its 2 errors", causes)
+	}
+}
@@ -0,0 +1,125 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/grpclog"
+)
+
+// GRPCLogger implements grpclog.LoggerV2 on top of a Logger.
+// gRPC logs a lot of connection details as info, these are logged as debug.
+// Warnings, errors and fatal entries keep their level.
+type GRPCLogger struct {
+	l         *Logger
+	verbosity int
+}
+
+var (
+	_ grpclog.LoggerV2      = (*GRPCLogger)(nil)
+	_ grpclog.DepthLoggerV2 = (*GRPCLogger)(nil)
+)
+
+// NewGRPCLogger creates an adapter for gRPC's internal logging.
+// verbosity is the highest level for which V returns true, provided debug
+// entries are enabled for l (see GRPC_GO_LOG_VERBOSITY_LEVEL)
+func NewGRPCLogger(l *Logger, verbosity int) *GRPCLogger {
+	return &GRPCLogger{l: l, verbosity: verbosity}
+}
+
+// SetGRPCLogger installs the adapter for the logger named "grpc" as gRPC logger.
+// Must be called before any gRPC function is used.
+func SetGRPCLogger(base *Logger, verbosity int) {
+	if base == nil {
+		base = Default()
+	}
+	grpclog.SetLoggerV2(NewGRPCLogger(base.Named("grpc"), verbosity))
+}
+
+func (g *GRPCLogger) Info(args ...any) {
+	g.l.l.Debug(fmt.Sprint(args...))
+}
+
+func (g *GRPCLogger) Infoln(args ...any) {
+	g.l.l.Debug(sprintln(args...))
+}
+
+func (g *GRPCLogger) Infof(format string, args ...any) {
+	g.l.l.Debug(fmt.Sprintf(format, args...))
+}
+
+func (g *GRPCLogger) Warning(args ...any) {
+	g.l.l.Warn(fmt.Sprint(args...))
+}
+
+func (g *GRPCLogger) Warningln(args ...any) {
+	g.l.l.Warn(sprintln(args...))
+}
+
+func (g *GRPCLogger) Warningf(format string, args ...any) {
+	g.l.l.Warn(fmt.Sprintf(format, args...))
+}
+
+func (g *GRPCLogger) Error(args ...any) {
+	g.l.l.Error(fmt.Sprint(args...))
+}
+
+func (g *GRPCLogger) Errorln(args ...any) {
+	g.l.l.Error(sprintln(args...))
+}
+
+func (g *GRPCLogger) Errorf(format string, args ...any) {
+	g.l.l.Error(fmt.Sprintf(format, args...))
+}
+
+func (g *GRPCLogger) Fatal(args ...any) {
+	g.l.l.Fatal(fmt.Sprint(args...))
+}
+
+func (g *GRPCLogger) Fatalln(args ...any) {
+	g.l.l.Fatal(sprintln(args...))
+}
+
+func (g *GRPCLogger) Fatalf(format string, args ...any) {
+	g.l.l.Fatal(fmt.Sprintf(format, args...))
+}
+
+// the Depth variants are used by grpclog, depth is the number of stack frames
+// between the gRPC code and grpclog's own Depth function. This way the caller
+// shows the gRPC code.
+
+func (g *GRPCLogger) InfoDepth(depth int, args ...any) {
+	g.logDepth(depth, DebugLevel, args)
+}
+
+func (g *GRPCLogger) WarningDepth(depth int, args ...any) {
+	g.logDepth(depth, WarnLevel, args)
+}
+
+func (g *GRPCLogger) ErrorDepth(depth int, args ...any) {
+	g.logDepth(depth, ErrorLevel, args)
+}
+
+func (g *GRPCLogger) FatalDepth(depth int, args ...any) {
+	g.logDepth(depth, FatalLevel, args)
+}
+
+func (g *GRPCLogger) logDepth(depth int, lvl Level, args []any) {
+	// check first, adding the caller skip is not for free
+	if !g.l.l.Core().Enabled(lvl) {
+		return
+	}
+	// skip this method and grpclog's Depth function
+	g.l.l.WithOptions(zap.AddCallerSkip(depth+2)).Log(lvl, sprintln(args...))
+}
+
+// V reports whether entries of verbosity level lvl are logged.
+func (g *GRPCLogger) V(lvl int) bool {
+	return lvl <= g.verbosity && g.l.l.Core().Enabled(DebugLevel)
+}
+
+// fmt.Sprintln adds spaces between all operands and a trailing newline
+func sprintln(args ...any) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
@@ -0,0 +1,95 @@
+package log
+
+import (
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+type (
+	// loggerMatcher finds the best matching entry of the loggers config for a
+	// logger name, case is ignored (env vars can't express it). The patterns
+	// are compiled once per config.
+	loggerMatcher struct {
+		patterns []loggerPattern
+	}
+	loggerPattern struct {
+		key      string
+		segments []patternSegment
+	}
+	// a segment is either compared as plain string or as regular expression
+	patternSegment struct {
+		literal string
+		re      *regexp.Regexp
+	}
+)
+
+// patterns with invalid regular expressions are ignored
+func newLoggerMatcher(loggers map[string]LoggerConfig) *loggerMatcher {
+	ret := &loggerMatcher{patterns: make([]loggerPattern, 0, len(loggers))}
+	// sorted keys ensure a stable result if patterns have the same length
+	for _, key := range slices.Sorted(maps.Keys(loggers)) {
+		if p, err := compileLoggerPattern(key); err == nil {
+			ret.patterns = append(ret.patterns, p)
+		}
+	}
+	return ret
+}
+
+func compileLoggerPattern(key string) (loggerPattern, error) {
+	parts := strings.Split(key, ".")
+	ret := loggerPattern{key: key, segments: make([]patternSegment, len(parts))}
+	for i, part := range parts {
+		if regexp.QuoteMeta(part) == part {
+			ret.segments[i].literal = part
+			continue
+		}
+		re, err := regexp.Compile("(?i)^" + part + "$")
+		if err != nil {
+			return ret, err
+		}
+		ret.segments[i].re = re
+	}
+	return ret, nil
+}
+
+// bestMatch returns the key of the pattern that matches the most segments
+// of name. The pattern must not have more segments than name.
+// An empty string is returned if no pattern matches.
+func (m *loggerMatcher) bestMatch(name string) string {
+	if len(m.patterns) == 0 {
+		return ""
+	}
+	nameParts := strings.Split(name, ".")
+	var best *loggerPattern
+	for i := range m.patterns {
+		p := &m.patterns[i]
+		if len(p.segments) > len(nameParts) ||
+			(best != nil && len(p.segments) <= len(best.segments)) {
+
+			continue
+		}
+		if p.matches(nameParts) {
+			best = p
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	return best.key
+}
+
+func (p *loggerPattern) matches(nameParts []string) bool {
+	for i := range p.segments {
+		seg := &p.segments[i]
+		if seg.re == nil {
+			if !strings.EqualFold(seg.literal, nameParts[i]) {
+				return false
+			}
+		} else if !seg.re.MatchString(nameParts[i]) {
+			return false
+		}
+	}
+	return true
+}
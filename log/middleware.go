@@ -0,0 +1,100 @@
+package log
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+)
+
+// HTTPMiddleware puts a child of base with request and trace fields into the
+// request context. Handlers get it via GetFromContext.
+// If base is nil the default logger is used.
+// Note: the trace fields are only available if the middleware is called after
+// the otelhttp handler
+func HTTPMiddleware(base *Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fields := []Field{
+				String("method", r.Method),
+				String("url", r.URL.String()),
+				String("remoteAddr", r.RemoteAddr),
+			}
+			ctx := r.Context()
+			l := requestLogger(ctx, base, fields)
+			next.ServeHTTP(w, r.WithContext(AddToContext(ctx, l)))
+		})
+	}
+}
+
+// UnaryServerInterceptor puts a child of base with request and trace fields
+// into the context passed to the handler.
+// If base is nil the default logger is used.
+//
+//nolint:whitespace // editor/linter issue
+func UnaryServerInterceptor(base *Logger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		l := requestLogger(ctx, base, grpcFields(ctx, info.FullMethod))
+		return handler(AddToContext(ctx, l), req)
+	}
+}
+
+// StreamServerInterceptor is the streaming variant of UnaryServerInterceptor
+//
+//nolint:whitespace // editor/linter issue
+func StreamServerInterceptor(base *Logger) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx := ss.Context()
+		l := requestLogger(ctx, base, grpcFields(ctx, info.FullMethod))
+		return handler(srv, &contextServerStream{
+			ServerStream: ss,
+			ctx:          AddToContext(ctx, l),
+		})
+	}
+}
+
+// requestLogger creates the child logger for a request.
+// If the context contains a valid span, the trace fields and the context itself
+// are added. The latter is used by the OTLP output to link the entries to the span.
+func requestLogger(ctx context.Context, base *Logger, fields []Field) *Logger {
+	if base == nil {
+		base = Default()
+	}
+	span := trace.SpanContextFromContext(ctx)
+	if span.IsValid() {
+		fields = append(fields,
+			Any("ctx", ctx),
+			String("trace_id", span.TraceID().String()),
+			String("span_id", span.SpanID().String()))
+	}
+	return base.With(fields...)
+}
+
+func grpcFields(ctx context.Context, fullMethod string) []Field {
+	fields := []Field{String("method", fullMethod)}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		fields = append(fields, String("remoteAddr", p.Addr.String()))
+	}
+	return fields
+}
+
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
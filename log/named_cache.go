@@ -0,0 +1,73 @@
+package log
+
+import (
+	"container/list"
+	"sync"
+)
+
+// maxNamedLoggers is the number of named loggers cached per logger created
+// by New.
+// Names built from request data (e.g. a path) would grow the cache without
+// bound otherwise.
+const maxNamedLoggers = 1024
+
+type (
+	// namedCache is a LRU cache of values (e.g. named loggers) by the full
+	// logger name
+	namedCache[V any] struct {
+		mu    sync.Mutex
+		size  int
+		items map[string]*list.Element
+		order *list.List // front: most recently used
+	}
+	namedEntry[V any] struct {
+		name  string
+		value V
+	}
+)
+
+func newNamedCache[V any](size int) *namedCache[V] {
+	return &namedCache[V]{
+		size:  size,
+		items: make(map[string]*list.Element),
+		order: list.New(),
+	}
+}
+
+func (c *namedCache[V]) get(name string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[name]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(e)
+	//nolint:errcheck // by design
+	return e.Value.(*namedEntry[V]).value, true
+}
+
+// add stores v unless name is already cached. It returns the cached value.
+func (c *namedCache[V]) add(name string, v V) V {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[name]; ok {
+		c.order.MoveToFront(e)
+		//nolint:errcheck // by design
+		return e.Value.(*namedEntry[V]).value
+	}
+	c.items[name] = c.order.PushFront(&namedEntry[V]{name: name, value: v})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		//nolint:errcheck // by design
+		delete(c.items, oldest.Value.(*namedEntry[V]).name)
+	}
+	return v
+}
+
+func (c *namedCache[V]) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
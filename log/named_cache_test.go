@@ -0,0 +1,63 @@
+package log
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNamedCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newNamedCache[*Logger](2)
+	a, b, d := &Logger{}, &Logger{}, &Logger{}
+	c.add("a", a)
+	c.add("b", b)
+	if got, _ := c.get("a"); got != a {
+		t.Fatal("expected a to be cached")
+	}
+	c.add("d", d) // evicts b, a was used more recently
+	if _, ok := c.get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	if got, _ := c.get("a"); got != a {
+		t.Error("expected a to be kept")
+	}
+	if got := c.add("d", &Logger{}); got != d {
+		t.Error("add should return the cached logger")
+	}
+	if c.len() != 2 {
+		t.Errorf("len = %d, want 2", c.len())
+	}
+}
+
+func TestNamedIsBounded(t *testing.T) {
+	root := New(WithLogConfig(benchmarkConfig()))
+	for i := range maxNamedLoggers + 10 {
+		root.Named(fmt.Sprintf("req-%d", i))
+	}
+	if n := root.shared.named.len(); n != maxNamedLoggers {
+		t.Errorf("cached children = %d, want %d", n, maxNamedLoggers)
+	}
+	if root.Named("demoLogger") != root.Named("demoLogger") {
+		t.Error("expected the same logger for the same name")
+	}
+}
+
+func TestNamedWithFieldsUsesCache(t *testing.T) {
+	root := New(WithLogConfig(benchmarkConfig()))
+	named := root.Named("demoLogger")
+	for i := range 3 {
+		child := root.With(Int("request", i)).Named("demoLogger")
+		if child.base != named {
+			t.Fatal("expected the cached logger as base of the child")
+		}
+		if len(child.fields) != 1 {
+			t.Errorf("fields = %v, want the request field", child.fields)
+		}
+	}
+	nested := root.Named("web").With(String("user", "x")).Named("handler")
+	if nested.base != root.Named("web").Named("handler") {
+		t.Error("expected the cached logger for web.handler")
+	}
+	if root.shared.named.len() != 3 {
+		t.Errorf("cached = %d, want 3", root.shared.named.len())
+	}
+}
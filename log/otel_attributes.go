@@ -0,0 +1,326 @@
+package log
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap/zapcore"
+)
+
+// convertFields converts zap fields to OTLP attributes.
+// A field containing a context.Context is not converted but returned as ctx,
+// so it can be used to link the log record to a span.
+func convertFields(fields []Field) (context.Context, []attribute.KeyValue) {
+	var ctx context.Context
+	enc := newAttrEncoder(len(fields))
+	for i := range fields {
+		if fieldCtx, ok := fields[i].Interface.(context.Context); ok {
+			ctx = fieldCtx
+			continue
+		}
+		fields[i].AddTo(enc)
+		if err, ok := fields[i].Interface.(error); ok && err != nil &&
+			fields[i].Type == zapcore.ErrorType {
+
+			extra, _ := exceptionFields(err)
+			for j := range extra {
+				extra[j].AddTo(enc)
+			}
+		}
+	}
+	return ctx, enc.result()
+}
+
+type (
+	// attrEncoder is a zapcore.ObjectEncoder which collects OTLP attributes
+	attrEncoder struct {
+		attrs []attribute.KeyValue
+		// namespaces opened via OpenNamespace, innermost last
+		namespaces []attrNamespace
+	}
+	attrNamespace struct {
+		key    string
+		parent []attribute.KeyValue
+	}
+	// attrArrayEncoder is a zapcore.ArrayEncoder which collects OTLP values
+	attrArrayEncoder struct {
+		values []attribute.Value
+	}
+)
+
+var (
+	_ zapcore.ObjectEncoder = (*attrEncoder)(nil)
+	_ zapcore.ArrayEncoder  = (*attrArrayEncoder)(nil)
+)
+
+func newAttrEncoder(size int) *attrEncoder {
+	return &attrEncoder{attrs: make([]attribute.KeyValue, 0, size)}
+}
+
+// result closes all open namespaces and returns the collected attributes
+func (e *attrEncoder) result() []attribute.KeyValue {
+	for i := len(e.namespaces) - 1; i >= 0; i-- {
+		ns := e.namespaces[i]
+		e.attrs = append(ns.parent, attribute.Map(ns.key, e.attrs...))
+	}
+	e.namespaces = nil
+	return e.attrs
+}
+
+func (e *attrEncoder) add(kv attribute.KeyValue) {
+	e.attrs = append(e.attrs, kv)
+}
+
+func (e *attrEncoder) AddArray(key string, v zapcore.ArrayMarshaler) error {
+	arr := &attrArrayEncoder{}
+	err := v.MarshalLogArray(arr)
+	e.add(attribute.Slice(key, arr.values...))
+	return err
+}
+
+func (e *attrEncoder) AddObject(key string, v zapcore.ObjectMarshaler) error {
+	obj := newAttrEncoder(0)
+	err := v.MarshalLogObject(obj)
+	e.add(attribute.Map(key, obj.result()...))
+	return err
+}
+
+func (e *attrEncoder) AddBinary(key string, v []byte) {
+	e.add(attribute.String(key, base64.StdEncoding.EncodeToString(v)))
+}
+
+func (e *attrEncoder) AddByteString(key string, v []byte) {
+	e.add(attribute.String(key, string(v)))
+}
+
+func (e *attrEncoder) AddBool(key string, v bool) {
+	e.add(attribute.Bool(key, v))
+}
+
+func (e *attrEncoder) AddComplex128(key string, v complex128) {
+	e.add(attribute.String(key, fmt.Sprint(v)))
+}
+
+func (e *attrEncoder) AddComplex64(key string, v complex64) {
+	e.AddComplex128(key, complex128(v))
+}
+
+func (e *attrEncoder) AddDuration(key string, v time.Duration) {
+	e.add(attribute.String(key, v.String()))
+}
+
+func (e *attrEncoder) AddFloat64(key string, v float64) {
+	e.add(attribute.Float64(key, v))
+}
+
+func (e *attrEncoder) AddFloat32(key string, v float32) {
+	e.AddFloat64(key, float64(v))
+}
+
+func (e *attrEncoder) AddInt(key string, v int) {
+	e.AddInt64(key, int64(v))
+}
+
+func (e *attrEncoder) AddInt64(key string, v int64) {
+	e.add(attribute.Int64(key, v))
+}
+
+func (e *attrEncoder) AddInt32(key string, v int32) {
+	e.AddInt64(key, int64(v))
+}
+
+func (e *attrEncoder) AddInt16(key string, v int16) {
+	e.AddInt64(key, int64(v))
+}
+
+func (e *attrEncoder) AddInt8(key string, v int8) {
+	e.AddInt64(key, int64(v))
+}
+
+func (e *attrEncoder) AddString(key, v string) {
+	e.add(attribute.String(key, v))
+}
+
+func (e *attrEncoder) AddTime(key string, v time.Time) {
+	e.add(attribute.String(key, v.Format(time.RFC3339Nano)))
+}
+
+func (e *attrEncoder) AddUint(key string, v uint) {
+	e.AddUint64(key, uint64(v))
+}
+
+func (e *attrEncoder) AddUint64(key string, v uint64) {
+	e.add(attribute.KeyValue{Key: attribute.Key(key), Value: uint64Value(v)})
+}
+
+func (e *attrEncoder) AddUint32(key string, v uint32) {
+	e.AddInt64(key, int64(v))
+}
+
+func (e *attrEncoder) AddUint16(key string, v uint16) {
+	e.AddInt64(key, int64(v))
+}
+
+func (e *attrEncoder) AddUint8(key string, v uint8) {
+	e.AddInt64(key, int64(v))
+}
+
+func (e *attrEncoder) AddUintptr(key string, v uintptr) {
+	e.AddUint64(key, uint64(v))
+}
+
+func (e *attrEncoder) AddReflected(key string, v interface{}) error {
+	e.add(attribute.KeyValue{Key: attribute.Key(key), Value: reflectedValue(v)})
+	return nil
+}
+
+func (e *attrEncoder) OpenNamespace(key string) {
+	e.namespaces = append(e.namespaces, attrNamespace{key: key, parent: e.attrs})
+	e.attrs = make([]attribute.KeyValue, 0)
+}
+
+func (a *attrArrayEncoder) add(v attribute.Value) {
+	a.values = append(a.values, v)
+}
+
+func (a *attrArrayEncoder) AppendArray(v zapcore.ArrayMarshaler) error {
+	arr := &attrArrayEncoder{}
+	err := v.MarshalLogArray(arr)
+	a.add(attribute.SliceValue(arr.values...))
+	return err
+}
+
+func (a *attrArrayEncoder) AppendObject(v zapcore.ObjectMarshaler) error {
+	obj := newAttrEncoder(0)
+	err := v.MarshalLogObject(obj)
+	a.add(attribute.MapValue(obj.result()...))
+	return err
+}
+
+func (a *attrArrayEncoder) AppendReflected(v interface{}) error {
+	a.add(reflectedValue(v))
+	return nil
+}
+
+func (a *attrArrayEncoder) AppendBool(v bool) {
+	a.add(attribute.BoolValue(v))
+}
+
+func (a *attrArrayEncoder) AppendByteString(v []byte) {
+	a.add(attribute.StringValue(string(v)))
+}
+
+func (a *attrArrayEncoder) AppendComplex128(v complex128) {
+	a.add(attribute.StringValue(fmt.Sprint(v)))
+}
+
+func (a *attrArrayEncoder) AppendComplex64(v complex64) {
+	a.AppendComplex128(complex128(v))
+}
+
+func (a *attrArrayEncoder) AppendDuration(v time.Duration) {
+	a.add(attribute.StringValue(v.String()))
+}
+
+func (a *attrArrayEncoder) AppendFloat64(v float64) {
+	a.add(attribute.Float64Value(v))
+}
+
+func (a *attrArrayEncoder) AppendFloat32(v float32) {
+	a.AppendFloat64(float64(v))
+}
+
+func (a *attrArrayEncoder) AppendInt(v int) {
+	a.AppendInt64(int64(v))
+}
+
+func (a *attrArrayEncoder) AppendInt64(v int64) {
+	a.add(attribute.Int64Value(v))
+}
+
+func (a *attrArrayEncoder) AppendInt32(v int32) {
+	a.AppendInt64(int64(v))
+}
+
+func (a *attrArrayEncoder) AppendInt16(v int16) {
+	a.AppendInt64(int64(v))
+}
+
+func (a *attrArrayEncoder) AppendInt8(v int8) {
+	a.AppendInt64(int64(v))
+}
+
+func (a *attrArrayEncoder) AppendString(v string) {
+	a.add(attribute.StringValue(v))
+}
+
+func (a *attrArrayEncoder) AppendTime(v time.Time) {
+	a.add(attribute.StringValue(v.Format(time.RFC3339Nano)))
+}
+
+func (a *attrArrayEncoder) AppendUint(v uint) {
+	a.AppendUint64(uint64(v))
+}
+
+func (a *attrArrayEncoder) AppendUint64(v uint64) {
+	a.add(uint64Value(v))
+}
+
+func (a *attrArrayEncoder) AppendUint32(v uint32) {
+	a.AppendInt64(int64(v))
+}
+
+func (a *attrArrayEncoder) AppendUint16(v uint16) {
+	a.AppendInt64(int64(v))
+}
+
+func (a *attrArrayEncoder) AppendUint8(v uint8) {
+	a.AppendInt64(int64(v))
+}
+
+func (a *attrArrayEncoder) AppendUintptr(v uintptr) {
+	a.AppendUint64(uint64(v))
+}
+
+// OTLP has no unsigned integers, values exceeding int64 are sent as string
+func uint64Value(v uint64) attribute.Value {
+	if v > math.MaxInt64 {
+		return attribute.StringValue(fmt.Sprint(v))
+	}
+	return attribute.Int64Value(int64(v))
+}
+
+// reflectedValue is used for zap.Any and zap.Reflect fields
+func reflectedValue(v interface{}) attribute.Value {
+	switch val := v.(type) {
+	case nil:
+		return attribute.Value{}
+	case string:
+		return attribute.StringValue(val)
+	case bool:
+		return attribute.BoolValue(val)
+	case int:
+		return attribute.Int64Value(int64(val))
+	case int64:
+		return attribute.Int64Value(val)
+	case float64:
+		return attribute.Float64Value(val)
+	case []byte:
+		return attribute.StringValue(base64.StdEncoding.EncodeToString(val))
+	case error:
+		return attribute.StringValue(val.Error())
+	case fmt.Stringer:
+		return attribute.StringValue(val.String())
+	default:
+		// same representation as used by the zap JSON encoder
+		if b, err := json.Marshal(val); err == nil {
+			return attribute.StringValue(string(b))
+		}
+		return attribute.StringValue(fmt.Sprintf("%+v", val))
+	}
+}
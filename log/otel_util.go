@@ -1,14 +1,61 @@
 package log
 
 import (
+	"sync/atomic"
+
 	otellog "go.opentelemetry.io/otel/log"
 	"go.uber.org/zap/zapcore"
 )
 
 // use this to control minsev LogProcesso
-type minsevSeverity struct{ severity otellog.Severity }
+// the severity can only be lowered, it starts with the highest severity
+type minsevSeverity struct{ severity atomic.Int64 }
+
+func newMinsevSeverity() *minsevSeverity {
+	ret := &minsevSeverity{}
+	ret.severity.Store(int64(otellog.SeverityFatal4))
+	return ret
+}
+
+func (m *minsevSeverity) Severity() otellog.Severity {
+	return otellog.Severity(m.severity.Load())
+}
+
+func (m *minsevSeverity) lower(sev otellog.Severity) {
+	for {
+		cur := m.severity.Load()
+		if int64(sev) >= cur || m.severity.CompareAndSwap(cur, int64(sev)) {
+			return
+		}
+	}
+}
 
-func (m *minsevSeverity) Severity() otellog.Severity { return m.severity }
+// levelFilterCore applies the OTLP threshold of a logger to the otelzap core.
+// The minsev processor of the shared provider only filters by the lowest
+// threshold of all loggers.
+type levelFilterCore struct {
+	zapcore.Core
+	level Level
+}
+
+func (c *levelFilterCore) Enabled(lvl Level) bool {
+	return c.level.Enabled(lvl) && c.Core.Enabled(lvl)
+}
+
+func (c *levelFilterCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelFilterCore{Core: c.Core.With(fields), level: c.level}
+}
+
+//nolint:whitespace // editor/linter issue
+func (c *levelFilterCore) Check(
+	ent zapcore.Entry,
+	ce *zapcore.CheckedEntry,
+) *zapcore.CheckedEntry {
+	if !c.level.Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
 
 func convertLevel(level zapcore.Level) otellog.Severity {
 	switch level {
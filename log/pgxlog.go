@@ -0,0 +1,94 @@
+package log
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/jackc/pgx/v5/tracelog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PgxLogger implements tracelog.Logger on top of a Logger.
+// The data of an entry is added as fields, a valid span in ctx links the
+// entry to the trace.
+type PgxLogger struct {
+	l *Logger
+}
+
+var _ tracelog.Logger = (*PgxLogger)(nil)
+
+func NewPgxLogger(l *Logger) *PgxLogger {
+	return &PgxLogger{l: l}
+}
+
+// NewPgxTracer creates a tracer for pgx.ConnConfig which logs via the logger
+// named "pgx". The pgx log level is derived from the level of that logger,
+// so pgx doesn't prepare entries which would be discarded anyway.
+func NewPgxTracer(base *Logger) *tracelog.TraceLog {
+	if base == nil {
+		base = Default()
+	}
+	l := base.Named("pgx")
+	return &tracelog.TraceLog{
+		Logger:   NewPgxLogger(l),
+		LogLevel: pgxLogLevel(min(l.Level(), l.OtelLevel())),
+	}
+}
+
+//nolint:whitespace // editor/linter issue
+func (p *PgxLogger) Log(
+	ctx context.Context,
+	level tracelog.LogLevel,
+	msg string,
+	data map[string]any,
+) {
+	lvl := convertPgxLevel(level)
+	ce := p.l.l.Check(lvl, msg)
+	if ce == nil {
+		return
+	}
+	fields := make([]Field, 0, len(data)+1)
+	// sorted keys for a stable output
+	for _, k := range slices.Sorted(maps.Keys(data)) {
+		if err, ok := data[k].(error); ok && k == "err" {
+			fields = append(fields, ErrorField(err))
+			continue
+		}
+		fields = append(fields, Any(k, data[k]))
+	}
+	if ctx != nil && trace.SpanContextFromContext(ctx).IsValid() {
+		fields = append(fields, Any("ctx", ctx))
+	}
+	ce.Write(fields...)
+}
+
+func convertPgxLevel(level tracelog.LogLevel) Level {
+	switch level {
+	case tracelog.LogLevelTrace, tracelog.LogLevelDebug:
+		return DebugLevel
+	case tracelog.LogLevelInfo:
+		return InfoLevel
+	case tracelog.LogLevelWarn:
+		return WarnLevel
+	case tracelog.LogLevelError:
+		return ErrorLevel
+	case tracelog.LogLevelNone:
+		return DebugLevel
+	default:
+		return InfoLevel
+	}
+}
+
+func pgxLogLevel(lvl Level) tracelog.LogLevel {
+	switch {
+	case lvl <= DebugLevel:
+		return tracelog.LogLevelDebug
+	case lvl == InfoLevel:
+		return tracelog.LogLevelInfo
+	case lvl == WarnLevel:
+		return tracelog.LogLevelWarn
+	default:
+		return tracelog.LogLevelError
+	}
+}
@@ -0,0 +1,136 @@
+package log
+
+import (
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+type (
+	// RingBuffer keeps the last entries written by a logger in memory.
+	// Writers reserve a slot with an atomic counter and store the entry with an
+	// atomic pointer, so neither writers nor readers need a lock.
+	RingBuffer struct {
+		slots []atomic.Pointer[BufferedEntry]
+		next  atomic.Uint64 // sequence number of the next entry
+	}
+	BufferedEntry struct {
+		seq     uint64
+		Time    time.Time      `json:"time"`
+		Level   Level          `json:"level"`
+		Logger  string         `json:"logger,omitempty"`
+		Message string         `json:"msg"`
+		Caller  string         `json:"caller,omitempty"`
+		Fields  map[string]any `json:"fields,omitempty"`
+	}
+	// RingBufferFilter selects entries from a RingBuffer.
+	RingBufferFilter struct {
+		Level  Level  // minimum level
+		Logger string // logger name or prefix (up to a dot), empty: all loggers
+		Max    int    // maximum number of (newest) entries, 0: all
+	}
+	// ringBufferCore writes the entries to the buffer.
+	// Context fields are removed, they are of no use in the buffer.
+	ringBufferCore struct {
+		zapcore.LevelEnabler
+		buffer *RingBuffer
+		fields []zapcore.Field // added by With
+	}
+)
+
+func NewRingBuffer(size int) *RingBuffer {
+	return &RingBuffer{slots: make([]atomic.Pointer[BufferedEntry], max(size, 1))}
+}
+
+// returns nil if the ring buffer is not configured
+func newRingBuffer(cfg *RingBufferConfig) *RingBuffer {
+	if cfg == nil || cfg.Size <= 0 {
+		return nil
+	}
+	return NewRingBuffer(cfg.Size)
+}
+
+func (b *RingBuffer) add(e *BufferedEntry) {
+	e.seq = b.next.Add(1) - 1
+	b.slots[e.seq%uint64(len(b.slots))].Store(e)
+}
+
+// Entries returns the buffered entries matching filter, oldest first.
+func (b *RingBuffer) Entries(filter RingBufferFilter) []BufferedEntry {
+	end := b.next.Load()
+	size := uint64(len(b.slots))
+	start := uint64(0)
+	if end > size {
+		start = end - size
+	}
+	ret := make([]BufferedEntry, 0, end-start)
+	for seq := start; seq < end; seq++ {
+		e := b.slots[seq%size].Load()
+		// the slot may not be written yet or already be overwritten
+		if e == nil || e.seq != seq || !filter.matches(e) {
+			continue
+		}
+		ret = append(ret, *e)
+	}
+	if filter.Max > 0 && len(ret) > filter.Max {
+		ret = ret[len(ret)-filter.Max:]
+	}
+	return ret
+}
+
+func (f *RingBufferFilter) matches(e *BufferedEntry) bool {
+	if e.Level < f.Level {
+		return false
+	}
+	if f.Logger == "" || e.Logger == f.Logger {
+		return true
+	}
+	return strings.HasPrefix(e.Logger, f.Logger+".")
+}
+
+func (c *ringBufferCore) With(fields []zapcore.Field) zapcore.Core {
+	return &ringBufferCore{
+		LevelEnabler: c.LevelEnabler,
+		buffer:       c.buffer,
+		fields:       append(slices.Clone(c.fields), fields...),
+	}
+}
+
+//nolint:whitespace // editor/linter issue
+func (c *ringBufferCore) Check(
+	ent zapcore.Entry,
+	ce *zapcore.CheckedEntry,
+) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *ringBufferCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	e := &BufferedEntry{
+		Time:    ent.Time,
+		Level:   ent.Level,
+		Logger:  ent.LoggerName,
+		Message: ent.Message,
+	}
+	if ent.Caller.Defined {
+		e.Caller = ent.Caller.TrimmedPath()
+	}
+	if len(c.fields)+len(fields) > 0 {
+		enc := zapcore.NewMapObjectEncoder()
+		for _, f := range removeContextFields(slices.Concat(c.fields, fields)) {
+			f.AddTo(enc)
+		}
+		e.Fields = enc.Fields
+	}
+	c.buffer.add(e)
+	return nil
+}
+
+func (c *ringBufferCore) Sync() error {
+	return nil
+}
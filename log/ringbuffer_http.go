@@ -0,0 +1,44 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// NewRingBufferHandler serves the entries of b as JSON array, oldest first.
+// Query parameters:
+//   - level: minimum level (default: debug)
+//   - logger: logger name, includes its children (default: all)
+//   - n: maximum number of the newest entries (default: all)
+func NewRingBufferHandler(b *RingBuffer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if b == nil {
+			http.Error(w, "ring buffer not configured", http.StatusNotFound)
+			return
+		}
+		filter := RingBufferFilter{Level: DebugLevel}
+		q := r.URL.Query()
+		if v := q.Get("level"); v != "" {
+			lvl, err := ParseLevel(v)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			filter.Level = lvl
+		}
+		filter.Logger = q.Get("logger")
+		if v := q.Get("n"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				http.Error(w, "invalid value for n", http.StatusBadRequest)
+				return
+			}
+			filter.Max = n
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(b.Entries(filter)); err != nil {
+			Error("could not encode log entries", ErrorField(err))
+		}
+	})
+}
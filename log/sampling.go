@@ -0,0 +1,311 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	dropReasonSampling  = "sampling"
+	dropReasonRateLimit = "ratelimit"
+)
+
+type (
+	// dropCounter collects the number of entries dropped by sampling
+	// and rate limit per logger name and reason
+	dropCounter struct {
+		counts  sync.Map // dropKey -> *atomic.Int64
+		counter metric.Int64Counter
+	}
+	dropKey struct {
+		logger string
+		reason string
+	}
+)
+
+func newDropCounter(cfg *DropReportConfig) *dropCounter {
+	ret := &dropCounter{}
+	if cfg.Metric {
+		counter, err := otel.Meter("otlpdemo/log").Int64Counter("log.dropped",
+			metric.WithDescription("Number of log entries dropped by sampling "+
+				"or rate limit"),
+			metric.WithUnit("{entry}"))
+		if err == nil {
+			ret.counter = counter
+		}
+	}
+	return ret
+}
+
+func (d *dropCounter) add(logger, reason string) {
+	key := dropKey{logger: logger, reason: reason}
+	v, ok := d.counts.Load(key)
+	if !ok {
+		v, _ = d.counts.LoadOrStore(key, &atomic.Int64{})
+	}
+	//nolint:errcheck // by design
+	v.(*atomic.Int64).Add(1)
+	if d.counter != nil {
+		d.counter.Add(context.Background(), 1, metric.WithAttributes(
+			attribute.String("logger", logger),
+			attribute.String("reason", reason)))
+	}
+}
+
+// report writes the dropped counts since the last report to core.
+// core should not be sampled or rate limited itself.
+func (d *dropCounter) report(core zapcore.Core) {
+	d.counts.Range(func(k, v any) bool {
+		//nolint:errcheck // by design
+		count := v.(*atomic.Int64).Swap(0)
+		if count == 0 {
+			return true
+		}
+		//nolint:errcheck // by design
+		key := k.(dropKey)
+		ent := zapcore.Entry{
+			Level:      WarnLevel,
+			Time:       time.Now(),
+			LoggerName: key.logger,
+			Message:    "log entries dropped",
+		}
+		if ce := core.Check(ent, nil); ce != nil {
+			ce.Write(zap.String("reason", key.reason), zap.Int64("dropped", count))
+		}
+		return true
+	})
+}
+
+// reportPeriodically reports until ctx is done. The remaining counts are
+// reported before done is closed.
+//
+//nolint:whitespace // editor/linter issue
+func (d *dropCounter) reportPeriodically(
+	ctx context.Context,
+	core zapcore.Core,
+	interval time.Duration,
+	done chan<- struct{},
+) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			d.report(core)
+			return
+		case <-ticker.C:
+			d.report(core)
+		}
+	}
+}
+
+// limitState holds the sampling counts and the token bucket of a logger name.
+// It is kept in loggerShared, so all loggers of the same name (e.g. created by
+// With per request or created again after eviction from the cache) share it.
+type limitState struct {
+	sampler *sampleCounts // nil if disabled
+	bucket  *tokenBucket  // nil if disabled
+}
+
+// limitsFor returns the state of the logger name, it is created on first use.
+// Returns nil if neither sampling nor rate limit is configured.
+//
+//nolint:whitespace // editor/linter issue
+func (s *loggerShared) limitsFor(
+	name string,
+	sampling *SamplingConfig,
+	rateLimit *RateLimitConfig,
+) *limitState {
+	sample := sampling != nil && sampling.Tick > 0
+	limit := rateLimit != nil && rateLimit.PerSecond > 0
+	if !sample && !limit {
+		return nil
+	}
+	if cached, ok := s.limits.get(name); ok {
+		return cached
+	}
+	ret := &limitState{}
+	if sample {
+		ret.sampler = newSampleCounts(sampling)
+	}
+	if limit {
+		ret.bucket = newTokenBucket(rateLimit.PerSecond, rateLimit.Burst)
+	}
+	return s.limits.add(name, ret)
+}
+
+// applyLimits wraps the core of zl with the sampler and rate limiter of state.
+// Since the complete core is wrapped both zap and OTLP output are affected.
+func applyLimits(zl *zap.Logger, state *limitState, drops *dropCounter) *zap.Logger {
+	if state == nil {
+		return zl
+	}
+	return zl.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if state.bucket != nil {
+			core = &rateLimitCore{Core: core, bucket: state.bucket, drops: drops}
+		}
+		if state.sampler != nil {
+			core = &samplingCore{Core: core, counts: state.sampler, drops: drops}
+		}
+		return core
+	}))
+}
+
+type (
+	// sampleCounts counts the entries by level and message like the zap
+	// sampler: per tick the first Initial entries are logged, then every
+	// Thereafter entry.
+	sampleCounts struct {
+		mu         sync.Mutex
+		tick       time.Duration
+		initial    int
+		thereafter int
+		counts     map[sampleKey]*sampleCount
+	}
+	sampleKey struct {
+		level   Level
+		message string
+	}
+	sampleCount struct {
+		n       int
+		resetAt time.Time
+	}
+	// samplingCore drops entries not allowed by the sample counts
+	samplingCore struct {
+		zapcore.Core
+		counts *sampleCounts
+		drops  *dropCounter
+	}
+)
+
+func newSampleCounts(cfg *SamplingConfig) *sampleCounts {
+	return &sampleCounts{
+		tick:       cfg.Tick,
+		initial:    cfg.Initial,
+		thereafter: cfg.Thereafter,
+		counts:     make(map[sampleKey]*sampleCount),
+	}
+}
+
+func (s *sampleCounts) allow(ent zapcore.Entry) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := sampleKey{level: ent.Level, message: ent.Message}
+	c, ok := s.counts[key]
+	if !ok || !ent.Time.Before(c.resetAt) {
+		if !ok && len(s.counts) >= maxSampleKeys {
+			s.removeExpired(ent.Time)
+		}
+		c = &sampleCount{resetAt: ent.Time.Add(s.tick)}
+		s.counts[key] = c
+	}
+	c.n++
+	if c.n <= s.initial {
+		return true
+	}
+	return s.thereafter > 0 && (c.n-s.initial)%s.thereafter == 0
+}
+
+// maxSampleKeys limits the messages counted per logger name. If exceeded, the
+// counts of past ticks are removed.
+const maxSampleKeys = 4096
+
+func (s *sampleCounts) removeExpired(now time.Time) {
+	for k, c := range s.counts {
+		if !now.Before(c.resetAt) {
+			delete(s.counts, k)
+		}
+	}
+}
+
+func (c *samplingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &samplingCore{
+		Core:   c.Core.With(fields),
+		counts: c.counts,
+		drops:  c.drops,
+	}
+}
+
+//nolint:whitespace // editor/linter issue
+func (c *samplingCore) Check(
+	ent zapcore.Entry,
+	ce *zapcore.CheckedEntry,
+) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	if !c.counts.allow(ent) {
+		c.drops.add(ent.LoggerName, dropReasonSampling)
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// rateLimitCore drops entries if the token bucket is exhausted.
+// Entries with level DPanic and above are never dropped.
+type rateLimitCore struct {
+	zapcore.Core
+	bucket *tokenBucket
+	drops  *dropCounter
+}
+
+func (c *rateLimitCore) With(fields []zapcore.Field) zapcore.Core {
+	return &rateLimitCore{
+		Core:   c.Core.With(fields),
+		bucket: c.bucket,
+		drops:  c.drops,
+	}
+}
+
+//nolint:whitespace // editor/linter issue
+func (c *rateLimitCore) Check(
+	ent zapcore.Entry,
+	ce *zapcore.CheckedEntry,
+) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	if ent.Level < DPanicLevel && !c.bucket.allow(ent.Time) {
+		c.drops.add(ent.LoggerName, dropReasonRateLimit)
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	b := float64(max(burst, 1))
+	return &tokenBucket{rate: rate, burst: b, tokens: b}
+}
+
+func (b *tokenBucket) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.last.IsZero() && now.After(b.last) {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	if now.After(b.last) {
+		b.last = now
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,133 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestReportPeriodicallyStopsWithFinalReport(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	d := newDropCounter(&DropReportConfig{})
+	d.add("db", dropReasonRateLimit)
+	d.add("db", dropReasonRateLimit)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go d.reportPeriodically(ctx, core, time.Hour, done)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reporter not stopped")
+	}
+	entries := logs.FilterMessage("log entries dropped").All()
+	if len(entries) != 1 {
+		t.Fatalf("got %d reports, want 1", len(entries))
+	}
+	if got := entries[0].ContextMap()["dropped"]; got != int64(2) {
+		t.Errorf("dropped = %v, want 2", got)
+	}
+}
+
+func TestCloseStopsDropReport(t *testing.T) {
+	cfg := DefaultDevConfig()
+	cfg.Zap.OutputPaths = []string{"/dev/null"}
+	cfg.DropReport.Interval = time.Hour
+	l := New(WithLogConfig(cfg))
+	if l.shared.stopReport == nil {
+		t.Fatal("drop report not started")
+	}
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		//nolint:errcheck // /dev/null can't be synced on all platforms
+		l.Close()
+		l.Close() // a second call must not block
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not stop the drop report")
+	}
+}
+
+// dropped returns the number of entries of logger dropped for reason
+func dropped(l *Logger, logger, reason string) int64 {
+	v, ok := l.shared.drops.counts.Load(dropKey{logger: logger, reason: reason})
+	if !ok {
+		return 0
+	}
+	//nolint:errcheck // by design
+	return v.(*atomic.Int64).Load()
+}
+
+func TestLimitsAreSharedByName(t *testing.T) {
+	cfg := DefaultDevConfig()
+	cfg.Zap.OutputPaths = []string{"/dev/null"}
+	cfg.Loggers = map[string]LoggerConfig{
+		"db": {
+			Level:     "debug",
+			RateLimit: &RateLimitConfig{PerSecond: 0.001, Burst: 2},
+		},
+		"web": {
+			Level:    "debug",
+			Sampling: &SamplingConfig{Tick: time.Hour, Initial: 1},
+		},
+	}
+	root := New(WithLogConfig(cfg))
+	for i := range 2 {
+		req := root.With(Int("request", i))
+		for range 3 {
+			req.Named("db").Info("query")
+			req.Named("web").Info("request")
+		}
+	}
+	// 2 of 6 entries within the burst, 1 of 6 sampled
+	if got := dropped(root, "db", dropReasonRateLimit); got != 4 {
+		t.Errorf("rate limited = %d, want 4", got)
+	}
+	if got := dropped(root, "web", dropReasonSampling); got != 5 {
+		t.Errorf("sampled = %d, want 5", got)
+	}
+
+	// the state is kept if the logger is created again after eviction
+	for i := range maxNamedLoggers {
+		root.Named(fmt.Sprintf("req-%d", i))
+	}
+	if _, ok := root.shared.named.get("db"); ok {
+		t.Fatal("expected db to be evicted")
+	}
+	root.Named("db").Info("query")
+	if got := dropped(root, "db", dropReasonRateLimit); got != 5 {
+		t.Errorf("rate limited = %d, want 5", got)
+	}
+}
+
+func TestSampleCounts(t *testing.T) {
+	s := newSampleCounts(&SamplingConfig{Tick: time.Second, Initial: 2, Thereafter: 3})
+	now := time.Now()
+	var got []bool
+	for i := range 8 {
+		got = append(got, s.allow(zapcore.Entry{
+			Message: "msg",
+			Time:    now.Add(time.Duration(i) * time.Millisecond),
+		}))
+	}
+	want := []bool{true, true, false, false, true, false, false, true}
+	if !slices.Equal(got, want) {
+		t.Errorf("allowed = %v, want %v", got, want)
+	}
+	if !s.allow(zapcore.Entry{Message: "other", Time: now}) {
+		t.Error("expected other messages to be counted separately")
+	}
+	if !s.allow(zapcore.Entry{Message: "msg", Time: now.Add(time.Second)}) {
+		t.Error("expected the count to be reset after the tick")
+	}
+}
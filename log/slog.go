@@ -0,0 +1,172 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SlogHandler is a slog.Handler that routes the records into the zap core
+// of a Logger. Since the core is used as is, named logger levels, filters and
+// the OTLP tee are applied the same way as for the Logger itself.
+type SlogHandler struct {
+	core      zapcore.Core
+	name      string
+	addCaller bool
+}
+
+var _ slog.Handler = (*SlogHandler)(nil)
+
+func NewSlogHandler(l *Logger) *SlogHandler {
+	return &SlogHandler{
+		core:      l.l.Core(),
+		name:      l.l.Name(),
+		addCaller: !l.myCfg.cfg.Zap.DisableCaller,
+	}
+}
+
+// Slog returns a slog.Logger that writes to this logger
+func (l *Logger) Slog() *slog.Logger {
+	return slog.New(NewSlogHandler(l))
+}
+
+// SetSlogDefault makes l the backend of slog.Default()
+// Note: this also redirects the output of the standard library log package
+func SetSlogDefault(l *Logger) {
+	slog.SetDefault(l.Slog())
+}
+
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.core.Enabled(convertSlogLevel(level))
+}
+
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	ent := zapcore.Entry{
+		Level:      convertSlogLevel(r.Level),
+		Time:       r.Time,
+		LoggerName: h.name,
+		Message:    r.Message,
+	}
+	if h.addCaller && r.PC != 0 {
+		frames := runtime.CallersFrames([]uintptr{r.PC})
+		frame, _ := frames.Next()
+		ent.Caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+		ent.Caller.Function = frame.Function
+	}
+	ce := h.core.Check(ent, nil)
+	if ce == nil {
+		return nil
+	}
+
+	fields := make([]Field, 0, r.NumAttrs()+1)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendSlogAttr(fields, a)
+		return true
+	})
+	// the otelzap core takes the span context from a context.Context field
+	// (it is removed from the zap output by contextIgnoringCore)
+	if ctx != nil && trace.SpanContextFromContext(ctx).IsValid() {
+		fields = append(fields, zap.Any("ctx", ctx))
+	}
+	ce.Write(fields...)
+	return nil
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	fields := make([]Field, 0, len(attrs))
+	for _, a := range attrs {
+		fields = appendSlogAttr(fields, a)
+	}
+	ret := *h
+	ret.core = h.core.With(fields)
+	return &ret
+}
+
+// WithGroup nests all following attributes below name
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	ret := *h
+	ret.core = h.core.With([]Field{zap.Namespace(name)})
+	return &ret
+}
+
+func convertSlogLevel(level slog.Level) Level {
+	switch {
+	case level < slog.LevelInfo:
+		return DebugLevel
+	case level < slog.LevelWarn:
+		return InfoLevel
+	case level < slog.LevelError:
+		return WarnLevel
+	default:
+		return ErrorLevel
+	}
+}
+
+// appendSlogAttr converts a slog.Attr into zap fields.
+// Groups with an empty key are inlined, empty attributes are dropped
+// (as required by the slog.Handler contract)
+func appendSlogAttr(fields []Field, a slog.Attr) []Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+		if a.Key == "" {
+			for _, ga := range attrs {
+				fields = appendSlogAttr(fields, ga)
+			}
+			return fields
+		}
+		return append(fields, zap.Object(a.Key, slogGroup(attrs)))
+	case slog.KindString:
+		return append(fields, zap.String(a.Key, a.Value.String()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(a.Key, a.Value.Int64()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(a.Key, a.Value.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(a.Key, a.Value.Float64()))
+	case slog.KindBool:
+		return append(fields, zap.Bool(a.Key, a.Value.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(a.Key, a.Value.Duration()))
+	case slog.KindTime:
+		return append(fields, zap.Time(a.Key, a.Value.Time()))
+	case slog.KindAny, slog.KindLogValuer:
+		if err, ok := a.Value.Any().(error); ok {
+			return append(fields, zap.NamedError(a.Key, err))
+		}
+		return append(fields, zap.Any(a.Key, a.Value.Any()))
+	default:
+		return append(fields, zap.Any(a.Key, a.Value.Any()))
+	}
+}
+
+// slogGroup encodes the attributes of a slog group as zap object
+type slogGroup []slog.Attr
+
+func (g slogGroup) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	fields := make([]Field, 0, len(g))
+	for _, a := range g {
+		fields = appendSlogAttr(fields, a)
+	}
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package log
+
+import (
+	"context"
+	"slices"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap/zapcore"
+)
+
+// spanEventCore adds log entries as events to the span found in the fields
+// (a field containing a context.Context) or in the fields added via With.
+// Entries with an error field are recorded via span.RecordError.
+type spanEventCore struct {
+	logger zapcore.LevelEnabler // level of the logger
+	level  zapcore.LevelEnabler
+	ctx    context.Context // from fields added via With
+	fields []zapcore.Field // added via With, converted for recording spans only
+}
+
+// NewSpanEventCore creates a core that records entries enabled by logger
+// (the level of the logger) with at least the given level as span events.
+// Entries without a recording span are ignored.
+func NewSpanEventCore(logger, level zapcore.LevelEnabler) zapcore.Core {
+	return &spanEventCore{logger: logger, level: level}
+}
+
+func (c *spanEventCore) Enabled(lvl Level) bool {
+	return c.level.Enabled(lvl) && c.logger.Enabled(lvl)
+}
+
+func (c *spanEventCore) With(fields []zapcore.Field) zapcore.Core {
+	ctx := contextFromFields(fields)
+	if ctx == nil {
+		ctx = c.ctx
+	}
+	return &spanEventCore{
+		logger: c.logger,
+		level:  c.level,
+		ctx:    ctx,
+		fields: append(slices.Clip(c.fields), fields...),
+	}
+}
+
+//nolint:whitespace // editor/linter issue
+func (c *spanEventCore) Check(
+	ent zapcore.Entry,
+	ce *zapcore.CheckedEntry,
+) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *spanEventCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ctx := contextFromFields(fields)
+	if ctx == nil {
+		ctx = c.ctx
+	}
+	if ctx == nil {
+		return nil
+	}
+	span := trace.SpanFromContext(ctx)
+	if !span.SpanContext().IsValid() || !span.IsRecording() {
+		return nil
+	}
+
+	// the fields are only converted for recording spans
+	var errs []error
+	other := make([]zapcore.Field, 0, len(fields))
+	for i := range fields {
+		if _, ok := fields[i].Interface.(context.Context); ok {
+			continue
+		}
+		if fields[i].Type == zapcore.ErrorType {
+			if err, ok := fields[i].Interface.(error); ok && err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		other = append(other, fields[i])
+	}
+	_, attrs := convertFields(other)
+	_, withAttrs := convertFields(c.fields)
+	attrs = append(flattenAttributes(attrs), flattenAttributes(withAttrs)...)
+	attrs = append(attrs,
+		attribute.String("log.severity", ent.Level.String()),
+		attribute.String("log.message", ent.Message))
+	if ent.LoggerName != "" {
+		attrs = append(attrs, attribute.String("log.logger", ent.LoggerName))
+	}
+
+	if len(errs) == 0 {
+		span.AddEvent(ent.Message, trace.WithAttributes(attrs...))
+		return nil
+	}
+	for _, err := range errs {
+		opts := []trace.EventOption{trace.WithAttributes(attrs...)}
+		// RecordError uses the type of err as exception.type
+		err = unwrapFieldError(err)
+		if causes := errorCauses(err); len(causes) > 1 {
+			opts = append(opts, trace.WithAttributes(
+				attribute.StringSlice("exception.causes", formatCauses(causes))))
+		}
+		// prefer the stack of the error creation (NewErrorf), then the stack
+		// created by zap, it doesn't contain the zap internals
+		if stack := errorStack(err); stack != "" {
+			opts = append(opts, trace.WithAttributes(
+				semconv.ExceptionStacktrace(stack)))
+		} else if ent.Stack != "" {
+			opts = append(opts, trace.WithAttributes(
+				semconv.ExceptionStacktrace(ent.Stack)))
+		} else {
+			opts = append(opts, trace.WithStackTrace(true))
+		}
+		span.RecordError(err, opts...)
+	}
+	return nil
+}
+
+func (c *spanEventCore) Sync() error {
+	return nil
+}
+
+// contextFromFields returns the last context.Context of the fields (if any)
+func contextFromFields(fields []zapcore.Field) context.Context {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if ctx, ok := fields[i].Interface.(context.Context); ok {
+			return ctx
+		}
+	}
+	return nil
+}
+
+// span attributes don't support maps and slices of mixed types.
+// Maps are flattened to dotted keys, slices are converted to strings.
+func flattenAttributes(attrs []attribute.KeyValue) []attribute.KeyValue {
+	ret := make([]attribute.KeyValue, 0, len(attrs))
+	var flatten func(prefix string, kvs []attribute.KeyValue)
+	flatten = func(prefix string, kvs []attribute.KeyValue) {
+		for _, kv := range kvs {
+			key := prefix + string(kv.Key)
+			if kv.Value.Type() == attribute.MAP {
+				flatten(key+".", kv.Value.AsMap())
+				continue
+			}
+			if kv.Value.Type() == attribute.SLICE {
+				ret = append(ret, attribute.String(key, kv.Value.Emit()))
+				continue
+			}
+			ret = append(ret,
+				attribute.KeyValue{Key: attribute.Key(key), Value: kv.Value})
+		}
+	}
+	flatten("", attrs)
+	return ret
+}
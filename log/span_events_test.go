@@ -0,0 +1,112 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSpanEventCoreRespectsLoggerLevel(t *testing.T) {
+	c := NewSpanEventCore(zapcore.WarnLevel, zapcore.DebugLevel)
+	if c.Enabled(zapcore.InfoLevel) {
+		t.Error("info should be disabled by the logger level")
+	}
+	if !c.Enabled(zapcore.WarnLevel) {
+		t.Error("warn should be enabled")
+	}
+	c = NewSpanEventCore(zapcore.DebugLevel, zapcore.ErrorLevel)
+	if c.Enabled(zapcore.WarnLevel) {
+		t.Error("warn should be disabled by the span event level")
+	}
+}
+
+func TestSpanEventCoreWrite(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	ctx, span := tp.Tracer("test").Start(context.Background(), "op")
+
+	l := zap.New(NewSpanEventCore(zapcore.DebugLevel, zapcore.InfoLevel))
+	l.Info("without context")
+	l.Info("with context", zap.Any("ctx", ctx), zap.String("key", "value"))
+	l.Error("failed", zap.Any("ctx", ctx), zap.Error(errors.New("boom")))
+	l.Debug("below span event level", zap.Any("ctx", ctx))
+	span.End()
+	// spans not recording are ignored
+	notRecording := trace.ContextWithSpanContext(context.Background(),
+		span.SpanContext())
+	l.Info("not recording", zap.Any("ctx", notRecording))
+
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("spans = %d, want 1", len(spans))
+	}
+	events := spans[0].Events()
+	if len(events) != 2 {
+		t.Fatalf("events = %d, want 2: %v", len(events), events)
+	}
+	if events[0].Name != "with context" {
+		t.Errorf("event name = %q", events[0].Name)
+	}
+	found := false
+	for _, kv := range events[0].Attributes {
+		if kv.Key == "key" && kv.Value.AsString() == "value" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("missing attribute key=value in %v", events[0].Attributes)
+	}
+	if events[1].Name != "exception" {
+		t.Errorf("event name = %q, want exception", events[1].Name)
+	}
+}
+
+// countingObject counts its conversions
+type countingObject struct{ n int }
+
+func (o *countingObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	o.n++
+	enc.AddInt("n", o.n)
+	return nil
+}
+
+func TestSpanEventCoreConvertsWithFieldsLazily(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	ctx, span := tp.Tracer("test").Start(context.Background(), "op")
+
+	obj := &countingObject{}
+	l := zap.New(NewSpanEventCore(zapcore.DebugLevel, zapcore.InfoLevel)).
+		With(zap.Object("obj", obj))
+	l.Info("without context")
+	l.With(zap.Any("ctx", trace.ContextWithSpanContext(context.Background(),
+		span.SpanContext()))).Info("not recording")
+	if obj.n != 0 {
+		t.Errorf("fields converted %d times without a recording span", obj.n)
+	}
+	l.With(zap.Any("ctx", ctx)).Info("recording")
+	span.End()
+	if obj.n != 1 {
+		t.Errorf("fields converted %d times, want 1", obj.n)
+	}
+
+	events := rec.Ended()[0].Events()
+	if len(events) != 1 {
+		t.Fatalf("events = %d, want 1: %v", len(events), events)
+	}
+	found := false
+	for _, kv := range events[0].Attributes {
+		if kv.Key == "obj.n" && kv.Value.AsInt64() == 1 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("missing attribute obj.n=1 in %v", events[0].Attributes)
+	}
+}
@@ -2,10 +2,9 @@ package log
 
 import (
 	"context"
-	"maps"
-	"regexp"
 	"slices"
-	"strings"
+	"sync"
+	"sync/atomic"
 
 	"go.opentelemetry.io/contrib/bridges/otelzap"
 	"go.opentelemetry.io/contrib/processors/minsev"
@@ -20,10 +19,30 @@ type (
 	Field  = zap.Field
 	Logger struct {
 		l             *zap.Logger // zap ensure that zap.Logger is safe for concurrent use
-		level         Level
+		level         Level       // console level
+		otelLevel     Level       // threshold for OTLP output
 		zapConfig     *zap.Config
-		loggerConfigs map[string]string
+		loggerConfigs map[string]LoggerConfig
 		myCfg         *loggerConfig
+		shared        *loggerShared
+		fields        []Field // added by With, kept for named children
+		base          *Logger // the logger without fields (nil: no fields)
+	}
+	// state shared by a logger created by New and all its named children
+	loggerShared struct {
+		matcher *loggerMatcher
+		named   *namedCache[*Logger] // full logger name -> logger without fields
+		// sampling and rate limit state, kept if a logger is created again
+		limits  *namedCache[*limitState]
+		drops   *dropCounter
+		entries *entryCounter // nil if disabled
+		ring    *RingBuffer   // nil if disabled
+		// stops the drop reports and waits for the last one (nil if disabled)
+		stopReport func()
+
+		mu           sync.Mutex
+		provider     *sdklog.LoggerProvider // shared by all loggers for OTLP output
+		otelSeverity *minsevSeverity        // lowest OTLP threshold in use
 	}
 	LevelEnablerFunc func(lvl Level) bool
 
@@ -85,14 +104,6 @@ var (
 	Duration    = zap.Duration
 	Durationp   = zap.Durationp
 	Any         = zap.Any
-
-	Info   = std.Info
-	Warn   = std.Warn
-	Error  = std.Error
-	DPanic = std.DPanic
-	Panic  = std.Panic
-	Fatal  = std.Fatal
-	Debug  = std.Debug
 )
 
 const (
@@ -107,7 +118,13 @@ const (
 	DebugLevel Level = zap.DebugLevel // -1
 )
 
-var std = New()
+// std holds the default logger. It is replaced via ResetDefault while other
+// goroutines may be logging, so every access has to go through the pointer.
+var std atomic.Pointer[Logger]
+
+func init() {
+	std.Store(New())
+}
 
 func New(opts ...ConfigOption) *Logger {
 	myCfg := newLoggerConfig(opts...)
@@ -118,9 +135,26 @@ func New(opts ...ConfigOption) *Logger {
 		cfg.Zap.Level = lvl
 	}
 
+	otelLevel := cfg.Zap.Level.Level()
+	if myCfg.otelLevel != "" {
+		lvl, _ := zap.ParseAtomicLevel(myCfg.otelLevel)
+		otelLevel = lvl.Level()
+	}
+
+	shared := &loggerShared{
+		matcher:      newLoggerMatcher(cfg.Loggers),
+		drops:        newDropCounter(&cfg.DropReport),
+		entries:      newEntryCounter(cfg.EntryMetric),
+		ring:         newRingBuffer(cfg.RingBuffer),
+		named:        newNamedCache[*Logger](maxNamedLoggers),
+		limits:       newNamedCache[*limitState](maxNamedLoggers),
+		otelSeverity: newMinsevSeverity(),
+	}
+
 	zapLogger, _ := cfg.Zap.Build()
 
-	zapLogger = combinedCores(zapLogger, "", myCfg, cfg.Zap.Level.Level())
+	zapLogger = combinedCores(zapLogger, "", myCfg,
+		shared.loggerProvider(myCfg, otelLevel), otelLevel, shared)
 
 	if cfg.Filters != nil {
 		// concatenate items to one string
@@ -128,39 +162,82 @@ func New(opts ...ConfigOption) *Logger {
 		for _, filter := range cfg.Filters {
 			filters += filter + " "
 		}
-		zapLogger = zap.New(zapfilter.NewFilteringCore(
-			zapLogger.Core(),
-			zapfilter.MustParseRules(filters)),
-		)
+		// invalid rules are reported by LoadConfig and Validate. If the config
+		// wasn't checked, the filters are ignored with a warning.
+		if filter, err := zapfilter.ParseRules(filters); err == nil {
+			zapLogger = zap.New(zapfilter.NewFilteringCore(zapLogger.Core(), filter))
+		} else {
+			zapLogger.Warn("Ignoring invalid log filters",
+				zap.Strings("filters", cfg.Filters), zap.Error(err))
+		}
 	}
+	if cfg.DropReport.Interval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		shared.stopReport = func() {
+			cancel()
+			<-done
+		}
+		// report via the core without limits, otherwise reports could be dropped
+		go shared.drops.reportPeriodically(ctx, zapLogger.Core(),
+			cfg.DropReport.Interval, done)
+	}
+	zapLogger = applyLimits(zapLogger,
+		shared.limitsFor("", cfg.Sampling, cfg.RateLimit), shared.drops)
+
 	logger := &Logger{
 		l:             zapLogger,
 		level:         cfg.Zap.Level.Level(),
+		otelLevel:     otelLevel,
 		zapConfig:     &cfg.Zap,
 		loggerConfigs: cfg.Loggers,
 		myCfg:         myCfg,
+		shared:        shared,
 	}
 	return logger
 }
 
 func Default() *Logger {
-	return std
+	return std.Load()
 }
 
-func ErrorField(err error) Field {
-	return zap.Error(err)
+// ResetDefault replaces the default logger used by the package functions.
+// Safe for concurrent use, nil is ignored.
+func ResetDefault(l *Logger) {
+	if l != nil {
+		std.Store(l)
+	}
 }
 
-// not safe for concurrent use
-func ResetDefault(l *Logger) {
-	std = l
-	Info = std.Info
-	Warn = std.Warn
-	Error = std.Error
-	DPanic = std.DPanic
-	Panic = std.Panic
-	Fatal = std.Fatal
-	Debug = std.Debug
+// The package functions log via the current default logger.
+// They call the zap logger directly to keep the caller skip of the methods.
+
+func Debug(msg string, fields ...Field) {
+	std.Load().l.Debug(msg, fields...)
+}
+
+func Info(msg string, fields ...Field) {
+	std.Load().l.Info(msg, fields...)
+}
+
+func Warn(msg string, fields ...Field) {
+	std.Load().l.Warn(msg, fields...)
+}
+
+func Error(msg string, fields ...Field) {
+	std.Load().l.Error(msg, fields...)
+}
+
+func DPanic(msg string, fields ...Field) {
+	std.Load().l.DPanic(msg, fields...)
+}
+
+func Panic(msg string, fields ...Field) {
+	std.Load().l.Panic(msg, fields...)
+}
+
+func Fatal(msg string, fields ...Field) {
+	std.Load().l.Fatal(msg, fields...)
 }
 
 type Option = zap.Option
@@ -182,6 +259,16 @@ func (l *Logger) Level() Level {
 	return l.level
 }
 
+// RingBuffer returns the buffer with the last entries, nil if not configured
+func (l *Logger) RingBuffer() *RingBuffer {
+	return l.shared.ring
+}
+
+// OtelLevel returns the threshold for OTLP output
+func (l *Logger) OtelLevel() Level {
+	return l.otelLevel
+}
+
 func (l *Logger) Debug(msg string, fields ...Field) {
 	l.l.Debug(msg, fields...)
 }
@@ -214,35 +301,86 @@ func (l *Logger) Log(lvl Level, msg string, fields ...Field) {
 	l.l.Log(lvl, msg, fields...)
 }
 
+// With creates a child logger which adds fields to all entries.
+// The child keeps the config of named loggers and the OTLP output.
+func (l *Logger) With(fields ...Field) *Logger {
+	if len(fields) == 0 {
+		return l
+	}
+	ret := *l
+	ret.l = l.l.With(fields...)
+	ret.fields = append(slices.Clone(l.fields), fields...)
+	ret.base = l.withoutFields()
+	return &ret
+}
+
+func (l *Logger) withoutFields() *Logger {
+	if l.base != nil {
+		return l.base
+	}
+	return l
+}
+
+// Named creates a child logger. The recently used children are cached by their
+// full name without the fields added by With, so calling Named for the same
+// name again is cheap, also for loggers created by With (e.g. per request).
 func (l *Logger) Named(name string) *Logger {
-	level := l.level // default level in case of no match or no valid log level
 	fullLoggerName := name
 	if l.l.Name() != "" {
 		fullLoggerName = l.l.Name() + "." + name
 	}
+	child, ok := l.shared.named.get(fullLoggerName)
+	if !ok {
+		child = l.shared.named.add(fullLoggerName,
+			l.withoutFields().newNamed(name, fullLoggerName))
+	}
+	return child.With(l.fields...)
+}
+
+func (l *Logger) newNamed(name, fullLoggerName string) *Logger {
+	level := l.level // default level in case of no match or no valid log level
+	otelLevel := l.otelLevel
 	zapL := l.l.Named(name)
-	loggers := slices.Collect(maps.Keys(l.loggerConfigs))
-	bestMatch := findBestMatch(loggers, fullLoggerName)
+	bestMatch := l.shared.matcher.bestMatch(fullLoggerName)
 	if bestMatch != "" {
-		if cfg, ok := l.loggerConfigs[bestMatch]; ok {
-			if cfg != "" {
-				lvl, _ := zap.ParseAtomicLevel(cfg)
-				level = lvl.Level()
-			}
+		cfg := l.loggerConfigs[bestMatch]
+		if cfg.Level != "" {
+			lvl, _ := zap.ParseAtomicLevel(cfg.Level)
+			level = lvl.Level()
+			otelLevel = level
+		}
+		if cfg.OtelLevel != "" {
+			lvl, _ := zap.ParseAtomicLevel(cfg.OtelLevel)
+			otelLevel = lvl.Level()
+		}
+		sampling := l.myCfg.cfg.Sampling
+		if cfg.Sampling != nil {
+			sampling = cfg.Sampling
+		}
+		rateLimit := l.myCfg.cfg.RateLimit
+		if cfg.RateLimit != nil {
+			rateLimit = cfg.RateLimit
 		}
 
 		myConfig := *l.zapConfig
 		myConfig.Level = zap.NewAtomicLevelAt(level)
 
 		lt, _ := myConfig.Build()
-		zapL = combinedCores(lt.Named(fullLoggerName), fullLoggerName, l.myCfg, level)
+		// combinedCores creates a new zap.Logger, so the name has to be set again
+		zapL = combinedCores(lt, fullLoggerName, l.myCfg,
+			l.shared.loggerProvider(l.myCfg, otelLevel), otelLevel, l.shared).
+			Named(fullLoggerName)
+		zapL = applyLimits(zapL,
+			l.shared.limitsFor(fullLoggerName, sampling, rateLimit), l.shared.drops)
 	}
 	return &Logger{
 		l:             zapL,
 		level:         level,
+		otelLevel:     otelLevel,
 		zapConfig:     l.zapConfig,
 		loggerConfigs: l.loggerConfigs,
 		myCfg:         l.myCfg,
+		shared:        l.shared,
 	}
 }
 
@@ -254,10 +392,14 @@ type contextIgnoringCore struct {
 }
 
 func (c *contextIgnoringCore) With(fields []zapcore.Field) zapcore.Core {
-	return &contextIgnoringCore{Core: c.Core.With(fields)}
+	return &contextIgnoringCore{Core: c.Core.With(removeContextFields(fields))}
 }
 
 func (c *contextIgnoringCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, removeContextFields(fields))
+}
+
+func removeContextFields(fields []zapcore.Field) []zapcore.Field {
 	cleanedFields := make([]zapcore.Field, 0, len(fields))
 	for _, f := range fields {
 		if _, ok := f.Interface.(context.Context); ok {
@@ -265,7 +407,7 @@ func (c *contextIgnoringCore) Write(ent zapcore.Entry, fields []zapcore.Field) e
 		}
 		cleanedFields = append(cleanedFields, f)
 	}
-	return c.Core.Write(ent, cleanedFields)
+	return cleanedFields
 }
 
 //nolint:whitespace // editor/linter issue
@@ -274,6 +416,11 @@ func (c *contextIgnoringCore) Check(
 	ce *zapcore.CheckedEntry,
 ) *zapcore.CheckedEntry {
 	// we just need to set add ourselves to the core
+	// the level has to be checked here, the tee may be enabled for a lower
+	// level because of the OTLP threshold
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
 	ret := ce.AddCore(ent, c)
 	return ret
 }
@@ -283,21 +430,23 @@ func combinedCores(
 	zl *zap.Logger,
 	name string,
 	myCfg *loggerConfig,
-	level Level,
+	provider *sdklog.LoggerProvider, // nil if no otel logging
+	otelLevel Level,
+	shared *loggerShared,
 ) *zap.Logger {
 	useCores := make([]zapcore.Core, 0)
-	if myCfg.telemetry != nil {
-		otelSeverity := &minsevSeverity{convertLevel(level)}
-		customLogger := myCfg.telemetry.CustomizedLogger(func(
-			exporter sdklog.Exporter,
-			downstream sdklog.Processor,
-		) sdklog.LoggerProviderOption {
-			proc := minsev.NewLogProcessor(downstream, otelSeverity)
-			return sdklog.WithProcessor(proc)
+	if provider != nil {
+		useCores = append(useCores, &levelFilterCore{
+			Core: &exceptionCore{
+				Core: otelzap.NewCore(name, otelzap.WithLoggerProvider(provider)),
+			},
+			level: otelLevel,
 		})
-
-		useCores = append(useCores, otelzap.NewCore(
-			name, otelzap.WithLoggerProvider(customLogger)))
+	}
+	if myCfg.cfg.SpanEventLevel != "" {
+		if lvl, err := ParseLevel(myCfg.cfg.SpanEventLevel); err == nil {
+			useCores = append(useCores, NewSpanEventCore(zl.Core(), lvl))
+		}
 	}
 	if myCfg.useZap {
 		if myCfg.removeContextFields {
@@ -311,6 +460,25 @@ func combinedCores(
 	combinedCore := zapcore.NewTee(
 		useCores...,
 	)
+	// the following cores handle the entries written by any of the cores above
+	var extraCores []zapcore.Core
+	if shared.entries != nil {
+		extraCores = append(extraCores,
+			&entryCounterCore{LevelEnabler: combinedCore, entries: shared.entries})
+	}
+	if shared.ring != nil {
+		var enabler zapcore.LevelEnabler = combinedCore
+		if cfg := myCfg.cfg.RingBuffer; cfg.Level != "" {
+			if lvl, err := ParseLevel(cfg.Level); err == nil {
+				enabler = lvl
+			}
+		}
+		extraCores = append(extraCores,
+			&ringBufferCore{LevelEnabler: enabler, buffer: shared.ring})
+	}
+	if len(extraCores) > 0 {
+		combinedCore = zapcore.NewTee(append(extraCores, combinedCore)...)
+	}
 
 	ret := zap.New(combinedCore,
 		zap.WithCaller(!myCfg.cfg.Zap.DisableCaller),
@@ -328,39 +496,48 @@ func (l *Logger) Sync() error {
 	return l.l.Sync()
 }
 
+// Close stops the background work of the logger (drop reports) and flushes
+// the output. Named children share it with the logger created by New, so Close
+// has to be called once for that logger only. Calling Close again is a no-op
+// except for the flush.
+func (l *Logger) Close() error {
+	if l.shared.stopReport != nil {
+		l.shared.stopReport()
+	}
+	return l.Sync()
+}
+
 func Sync() error {
-	if std != nil {
-		return std.Sync()
+	if l := std.Load(); l != nil {
+		return l.Sync()
 	}
 	return nil
 }
 
-func findBestMatch(stringsList []string, query string) string {
-	var bestMatch string
-	queryParts := strings.Split(query, ".")
-
-	for _, s := range stringsList {
-		sParts := strings.Split(s, ".")
-
-		// we can only match if the query has at least as many parts as the string
-		if len(sParts) <= len(queryParts) {
-			matches := true
-			for i := range sParts {
-				pattern := "^" + sParts[i] + "$"
-				matched, _ := regexp.MatchString(pattern, queryParts[i])
-				if !matched {
-					matches = false
-					break
-				}
-			}
-
-			if matches &&
-				(bestMatch == "" || len(sParts) > len(strings.Split(bestMatch, "."))) {
-
-				bestMatch = s
-			}
-		}
+// loggerProvider returns the provider for OTLP output.
+// The provider is created once and shared by all loggers, its minsev processor
+// is lowered to the given threshold if needed.
+// Returns nil if no telemetry is configured.
+//
+//nolint:whitespace // editor/linter issue
+func (s *loggerShared) loggerProvider(
+	myCfg *loggerConfig,
+	otelLevel Level,
+) *sdklog.LoggerProvider {
+	if myCfg.telemetry == nil {
+		return nil
 	}
-
-	return bestMatch
+	s.otelSeverity.lower(convertLevel(otelLevel))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.provider == nil {
+		s.provider = myCfg.telemetry.CustomizedLogger(func(
+			exporter sdklog.Exporter,
+			downstream sdklog.Processor,
+		) sdklog.LoggerProviderOption {
+			proc := minsev.NewLogProcessor(downstream, s.otelSeverity)
+			return sdklog.WithProcessor(proc)
+		})
+	}
+	return s.provider
 }
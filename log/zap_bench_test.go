@@ -0,0 +1,94 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/mpapenbr/otlpdemo/otel"
+)
+
+func benchmarkConfig() *Config {
+	cfg := DefaultDevConfig()
+	cfg.Zap.OutputPaths = []string{"/dev/null"}
+	cfg.Loggers = map[string]LoggerConfig{
+		"demoLogger":    {Level: "debug"},
+		"web":           {Level: "info"},
+		"web.handler.*": {Level: "debug"},
+		"db":            {Level: "warn"},
+		"db.[a-z]+":     {Level: "info"},
+		"grpc.*.client": {Level: "error"},
+	}
+	return cfg
+}
+
+// benchmarkNamed compares Named with creating the child without the cache
+func benchmarkNamed(b *testing.B, root *Logger) {
+	b.Helper()
+	for _, name := range []string{"demoLogger", "other", "web.handler.hello"} {
+		b.Run(name+"/uncached", func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				root.newNamed(name, name)
+			}
+		})
+		b.Run(name+"/cached", func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				root.Named(name)
+			}
+		})
+	}
+	b.Run("nested/cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			root.Named("web").Named("handler").Named("hello")
+		}
+	})
+	// e.g. the request scoped logger of the middleware
+	b.Run("with/cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			root.With(String("requestId", "1234")).Named("web.handler.hello")
+		}
+	})
+}
+
+func BenchmarkNamed(b *testing.B) {
+	benchmarkNamed(b, New(WithLogConfig(benchmarkConfig())))
+}
+
+func BenchmarkNamedWithTelemetry(b *testing.B) {
+	t, err := otel.SetupTelemetry(
+		otel.WithTelemetryOutput(otel.StdOut),
+		otel.WithStdoutWriter(io.Discard),
+		otel.WithRuntimeStats(false))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer t.Shutdown()
+	benchmarkNamed(b, New(WithLogConfig(benchmarkConfig()), WithTelemetry(t)))
+}
+
+func BenchmarkLoggerMatcher(b *testing.B) {
+	m := newLoggerMatcher(benchmarkConfig().Loggers)
+	b.ReportAllocs()
+	for b.Loop() {
+		m.bestMatch("web.handler.hello")
+	}
+}
+
+// disabled debug entries should cost the same with and without entry metric
+func BenchmarkDisabledDebug(b *testing.B) {
+	for _, enabled := range []bool{false, true} {
+		cfg := benchmarkConfig()
+		cfg.EntryMetric = enabled
+		l := New(WithLogConfig(cfg)).Named("db")
+		b.Run(fmt.Sprintf("entryMetric=%t", enabled), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				l.Debug("not logged", Int("value", 1))
+			}
+		})
+	}
+}
@@ -2,7 +2,6 @@ package log
 
 import (
 	"context"
-	"os"
 	"time"
 
 	"go.opentelemetry.io/otel/attribute"
@@ -13,56 +12,143 @@ import (
 
 type ZapWithOTLP struct {
 	l          *zap.Logger
-	level      zapcore.Level
 	otlpLogger otellog.Logger
 }
 
 // This is a simple wrapper for zap which also emits the logs to the OTLP logger
 // It is not a full implementation of the zap interface, but it is enough for
 // our use case
+// The zap logger is built from the zap config and level of the log config
+// (see WithLogConfig and WithLogLevel)
 // In order to link logs to traces the user must provide the span context by calling
-// the InfoContext method
-func NewZapWithContextBasedOTLP(provider otellog.LoggerProvider) (*ZapWithOTLP, error) {
-	cfg := zap.NewProductionConfig()
-	cfg.EncoderConfig.EncodeTime = func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
-		enc.AppendString(t.Format("2006-01-02T15:04:05.000Z0700"))
+// the ...Context methods
+//
+//nolint:whitespace // editor/linter issue
+func NewZapWithContextBasedOTLP(
+	provider otellog.LoggerProvider,
+	opts ...ConfigOption,
+) (*ZapWithOTLP, error) {
+	myCfg := newLoggerConfig(opts...)
+	zapCfg := myCfg.cfg.Zap
+	lvl, err := zap.ParseAtomicLevel(myCfg.level)
+	if err != nil {
+		return nil, err
 	}
+	zapCfg.Level = lvl
 
-	core := zapcore.NewCore(
-		zapcore.NewJSONEncoder(cfg.EncoderConfig),
-		zapcore.AddSync(os.Stdout),
-		zapcore.InfoLevel,
-	)
-
-	logger := zap.New(core, zap.AddCallerSkip(1))
+	// the public methods call log which calls zap
+	logger, err := zapCfg.Build(zap.AddCallerSkip(2))
+	if err != nil {
+		return nil, err
+	}
+	if myCfg.removeContextFields {
+		logger = logger.WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+			return &contextIgnoringCore{Core: c}
+		}))
+	}
 
 	ret := &ZapWithOTLP{
 		l:          logger,
-		level:      zapcore.InfoLevel,
 		otlpLogger: provider.Logger("contextbasedzap"),
 	}
 
 	return ret, nil
 }
 
+func (c *ZapWithOTLP) Debug(msg string, fields ...Field) {
+	c.log(context.Background(), zapcore.DebugLevel, msg, fields)
+}
+
 func (c *ZapWithOTLP) Info(msg string, fields ...Field) {
-	c.InfoContext(context.Background(), msg, fields...)
+	c.log(context.Background(), zapcore.InfoLevel, msg, fields)
 }
 
-func (c *ZapWithOTLP) createRecord(msg string) otellog.Record {
-	var r otellog.Record
-	r.SetTimestamp(time.Now())
-	r.SetSeverity(otellog.SeverityInfo)
-	r.SetBody(attribute.StringValue(msg))
-	return r
+func (c *ZapWithOTLP) Warn(msg string, fields ...Field) {
+	c.log(context.Background(), zapcore.WarnLevel, msg, fields)
+}
+
+func (c *ZapWithOTLP) Error(msg string, fields ...Field) {
+	c.log(context.Background(), zapcore.ErrorLevel, msg, fields)
+}
+
+func (c *ZapWithOTLP) DPanic(msg string, fields ...Field) {
+	c.log(context.Background(), zapcore.DPanicLevel, msg, fields)
+}
+
+func (c *ZapWithOTLP) Panic(msg string, fields ...Field) {
+	c.log(context.Background(), zapcore.PanicLevel, msg, fields)
+}
+
+func (c *ZapWithOTLP) Fatal(msg string, fields ...Field) {
+	c.log(context.Background(), zapcore.FatalLevel, msg, fields)
+}
+
+// DebugContext is a wrapper for the zap logger which also emits the logs
+// to the OTLP logger. The same applies to the other ...Context methods.
+func (c *ZapWithOTLP) DebugContext(ctx context.Context, msg string, fields ...Field) {
+	c.log(ctx, zapcore.DebugLevel, msg, fields)
 }
 
-// InfoContext is a wrapper for the zap logger which also emits the logs
-// to the OTLP logger
-// Note: here we don't convert the fields to OTLP attributes
 func (c *ZapWithOTLP) InfoContext(ctx context.Context, msg string, fields ...Field) {
-	if c.level.Enabled(zap.InfoLevel) {
-		c.l.Info(msg, fields...)
-		c.otlpLogger.Emit(ctx, c.createRecord(msg))
+	c.log(ctx, zapcore.InfoLevel, msg, fields)
+}
+
+func (c *ZapWithOTLP) WarnContext(ctx context.Context, msg string, fields ...Field) {
+	c.log(ctx, zapcore.WarnLevel, msg, fields)
+}
+
+func (c *ZapWithOTLP) ErrorContext(ctx context.Context, msg string, fields ...Field) {
+	c.log(ctx, zapcore.ErrorLevel, msg, fields)
+}
+
+func (c *ZapWithOTLP) DPanicContext(ctx context.Context, msg string, fields ...Field) {
+	c.log(ctx, zapcore.DPanicLevel, msg, fields)
+}
+
+func (c *ZapWithOTLP) PanicContext(ctx context.Context, msg string, fields ...Field) {
+	c.log(ctx, zapcore.PanicLevel, msg, fields)
+}
+
+func (c *ZapWithOTLP) FatalContext(ctx context.Context, msg string, fields ...Field) {
+	c.log(ctx, zapcore.FatalLevel, msg, fields)
+}
+
+func (c *ZapWithOTLP) Sync() error {
+	return c.l.Sync()
+}
+
+//nolint:whitespace // editor/linter issue
+func (c *ZapWithOTLP) log(
+	ctx context.Context,
+	lvl zapcore.Level,
+	msg string,
+	fields []Field,
+) {
+	ce := c.l.Check(lvl, msg)
+	if ce == nil {
+		return
+	}
+	// emit the OTLP record first, writing the zap entry may panic or exit
+	fieldCtx, attrs := convertFields(fields)
+	// a context passed as field is used if there is no explicit one
+	if fieldCtx != nil && ctx == context.Background() {
+		ctx = fieldCtx
 	}
+	c.otlpLogger.Emit(ctx, c.createRecord(ce.Entry, attrs))
+	ce.Write(fields...)
+}
+
+//nolint:whitespace // editor/linter issue
+func (c *ZapWithOTLP) createRecord(
+	ent zapcore.Entry,
+	attrs []attribute.KeyValue,
+) otellog.Record {
+	var r otellog.Record
+	r.SetTimestamp(ent.Time)
+	r.SetObservedTimestamp(time.Now())
+	r.SetSeverity(convertLevel(ent.Level))
+	r.SetSeverityText(ent.Level.String())
+	r.SetBody(attribute.StringValue(ent.Message))
+	r.AddAttributes(attrs...)
+	return r
 }
@@ -5,7 +5,9 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"io"
 	"os"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -45,13 +47,29 @@ type (
 		output       TelemetryOutput
 		logConfig    *logConfig
 		runtimeStats bool // enable runtime stats collection
+		certObserver CertObserver
+		stdoutWriter io.Writer // destination of the stdout exporters (nil: stdout)
 	}
 	Telemetry struct {
-		config  *config
-		metrics *sdkmetric.MeterProvider
-		traces  *sdktrace.TracerProvider
-		logs    *sdklog.LoggerProvider
+		config     *config
+		metrics    *sdkmetric.MeterProvider
+		traces     *sdktrace.TracerProvider
+		logs       *sdklog.LoggerProvider
+		customLogs *customLoggerProviders
 	}
+	// providers created by CustomizedLogger, these are flushed on Shutdown
+	customLoggerProviders struct {
+		mu        sync.Mutex
+		providers []*sdklog.LoggerProvider
+	}
+	// CertObserver gets the certificates loaded for the OTLP exporter
+	// (e.g. config.CertInventory)
+	CertObserver interface {
+		Register(source, usage string, certs ...*x509.Certificate)
+		// data is the content of a PEM file
+		RegisterPEM(source, usage string, data []byte)
+	}
+	nopCertObserver     struct{}
 	TelemetryOutput     int
 	TelemetryOption     func(cfg *config)
 	CustomizeLoggerFunc func(
@@ -107,6 +125,22 @@ func WithRuntimeStats(arg bool) TelemetryOption {
 	}
 }
 
+// WithCertObserver registers an observer which gets all certificates
+// loaded for the OTLP exporter (e.g. to monitor their expiry)
+func WithCertObserver(arg CertObserver) TelemetryOption {
+	return func(cfg *config) {
+		cfg.certObserver = arg
+	}
+}
+
+// WithStdoutWriter sets the destination of the stdout output
+// (e.g. io.Discard in benchmarks)
+func WithStdoutWriter(arg io.Writer) TelemetryOption {
+	return func(cfg *config) {
+		cfg.stdoutWriter = arg
+	}
+}
+
 func SetupTelemetry(opts ...TelemetryOption) (*Telemetry, error) {
 	cfg := config{
 		ctx:          context.Background(),
@@ -116,7 +150,7 @@ func SetupTelemetry(opts ...TelemetryOption) (*Telemetry, error) {
 	for _, opt := range opts {
 		opt(&cfg)
 	}
-	ret := Telemetry{config: &cfg}
+	ret := Telemetry{config: &cfg, customLogs: &customLoggerProviders{}}
 
 	if err := ret.setupMetrics(); err != nil {
 		return nil, err
@@ -140,6 +174,13 @@ func SetupTelemetry(opts ...TelemetryOption) (*Telemetry, error) {
 }
 
 func (t Telemetry) Shutdown() {
+	// custom providers share the downstream processor with the global provider.
+	// Only flush them, the downstream processor is shut down by t.logs
+	for _, p := range t.customLogs.list() {
+		if err := p.ForceFlush(context.Background()); err != nil {
+			fmt.Printf("flushing custom logs error:%+v\n", err)
+		}
+	}
 	if err := t.metrics.ForceFlush(context.Background()); err != nil {
 		fmt.Printf("flushing metrics error:%+v\n", err)
 	}
@@ -160,20 +201,43 @@ func (t Telemetry) Shutdown() {
 	}
 }
 
+// CustomizedLogger creates a LoggerProvider with the same resource as the
+// global provider. The provider is flushed by Shutdown.
+//
 //nolint:lll // readabilty
 func (t Telemetry) CustomizedLogger(opts ...CustomizeLoggerFunc) *sdklog.LoggerProvider {
-	lgOpts := make([]sdklog.LoggerProviderOption, len(opts))
-	for i, opt := range opts {
-		lgOpts[i] = opt(t.config.logConfig.exporter, t.config.logConfig.downstream)
+	lgOpts := make([]sdklog.LoggerProviderOption, 0, len(opts)+1)
+	lgOpts = append(lgOpts, sdklog.WithResource(initResource()))
+	for _, opt := range opts {
+		lgOpts = append(lgOpts,
+			opt(t.config.logConfig.exporter, t.config.logConfig.downstream))
 	}
-	return sdklog.NewLoggerProvider(lgOpts...)
+	provider := sdklog.NewLoggerProvider(lgOpts...)
+	t.customLogs.add(provider)
+	return provider
+}
+
+func (c *customLoggerProviders) add(p *sdklog.LoggerProvider) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.providers = append(c.providers, p)
+}
+
+func (c *customLoggerProviders) list() []*sdklog.LoggerProvider {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.providers)
 }
 
 func (t *Telemetry) setupMetrics() (err error) {
 	var exporter sdkmetric.Exporter
 	switch t.config.output {
 	case StdOut:
-		exporter, err = stdoutmetric.New()
+		var opts []stdoutmetric.Option
+		if t.config.stdoutWriter != nil {
+			opts = append(opts, stdoutmetric.WithWriter(t.config.stdoutWriter))
+		}
+		exporter, err = stdoutmetric.New(opts...)
 	case Grpc:
 		exporter, err = otlpmetricgrpc.New(t.config.ctx)
 	}
@@ -195,7 +259,11 @@ func (t *Telemetry) setupTraces() (err error) {
 	var exporter sdktrace.SpanExporter
 	switch t.config.output {
 	case StdOut:
-		exporter, err = stdouttrace.New()
+		var opts []stdouttrace.Option
+		if t.config.stdoutWriter != nil {
+			opts = append(opts, stdouttrace.WithWriter(t.config.stdoutWriter))
+		}
+		exporter, err = stdouttrace.New(opts...)
 	case Grpc:
 		exporter, err = otlptracegrpc.New(t.config.ctx)
 	}
@@ -226,13 +294,17 @@ func (t *Telemetry) setupLogs() (err error) {
 	var exporter sdklog.Exporter
 	switch t.config.output {
 	case StdOut:
-		exporter, err = stdoutlog.New()
+		var opts []stdoutlog.Option
+		if t.config.stdoutWriter != nil {
+			opts = append(opts, stdoutlog.WithWriter(t.config.stdoutWriter))
+		}
+		exporter, err = stdoutlog.New(opts...)
 	case Grpc:
 		// need to build TLS config from environment variables as workaround
 		// see buildTLSConfig
 		var grpcExpOpt []otlploggrpc.Option
 		var tlsCfg *tls.Config
-		tlsCfg, err = buildTLSConfig()
+		tlsCfg, err = buildTLSConfig(t.config.certObserver)
 		if err != nil {
 			return fmt.Errorf("failed to build TLS config: %w", err)
 		}
@@ -258,6 +330,7 @@ func (t *Telemetry) setupLogs() (err error) {
 
 	provider := sdklog.NewLoggerProvider(
 		sdklog.WithProcessor(proc),
+		sdklog.WithResource(initResource()),
 	)
 	global.SetLoggerProvider(provider)
 	t.logs = provider
@@ -277,7 +350,10 @@ func getEnv(key, component string) string {
 // this is a workaround for
 // https://github.com/open-telemetry/opentelemetry-go/issues/6661
 
-func buildTLSConfig() (*tls.Config, error) {
+func buildTLSConfig(observer CertObserver) (*tls.Config, error) {
+	if observer == nil {
+		observer = nopCertObserver{}
+	}
 	insecureEnv := getEnv("INSECURE", "LOGS")
 	caEnv := getEnv("CERTIFICATE", "LOGS")
 	keyEnv := getEnv("CLIENT_KEY", "LOGS")
@@ -295,6 +371,7 @@ func buildTLSConfig() (*tls.Config, error) {
 				return nil, err
 			}
 			tlsConfig.Certificates = []tls.Certificate{cert}
+			observer.Register(certEnv, "client", cert.Leaf)
 		}
 		if caEnv != "" {
 			caCert, err := os.ReadFile(caEnv)
@@ -305,6 +382,7 @@ func buildTLSConfig() (*tls.Config, error) {
 			if ok := caCertPool.AppendCertsFromPEM(caCert); !ok {
 				return nil, fmt.Errorf("failed to append server certificate")
 			}
+			observer.RegisterPEM(caEnv, "ca", caCert)
 			// this is used on the client side to verify the server certificate
 			tlsConfig.RootCAs = caCertPool
 		}
@@ -312,6 +390,10 @@ func buildTLSConfig() (*tls.Config, error) {
 	}
 }
 
+func (nopCertObserver) Register(string, string, ...*x509.Certificate) {}
+
+func (nopCertObserver) RegisterPEM(string, string, []byte) {}
+
 func initResource() *sdkresource.Resource {
 	initResourcesOnce.Do(func() {
 		extraResources, _ := sdkresource.New(